@@ -0,0 +1,88 @@
+package env
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// accessed records every key read through the getters, for Unused.
+var accessed sync.Map // key -> struct{}
+
+// envFold is the case-insensitive key index taken when the last applied
+// load had Options.CaseInsensitive set, or nil.
+var envFold atomic.Pointer[keyFold]
+
+// lookupEnv is os.LookupEnv that records the access and, after a
+// case-insensitive load, falls back to a key differing only in case.
+func lookupEnv(key string) (string, bool) {
+	accessed.Store(key, struct{}{})
+	v, ok := os.LookupEnv(key)
+	if f := envFold.Load(); !ok && f != nil {
+		if k := f.spelling(key); k != key {
+			accessed.Store(k, struct{}{})
+			return os.LookupEnv(k)
+		}
+	}
+	return v, ok
+}
+
+// keyFold maps upper-cased keys to the spelling in use, so case-insensitive
+// lookups don't rescan the environment.
+type keyFold map[string]string
+
+// newKeyFold indexes the process environment; the first spelling wins.
+func newKeyFold() keyFold {
+	f := keyFold{}
+	for _, kv := range os.Environ() {
+		if k, _, _ := strings.Cut(kv, "="); k != "" {
+			f.add(k)
+		}
+	}
+	return f
+}
+
+func (f keyFold) add(key string) {
+	if u := strings.ToUpper(key); f[u] == "" {
+		f[u] = key
+	}
+}
+
+// spelling returns the indexed spelling of key, or key itself.
+func (f keyFold) spelling(key string) string {
+	if k, ok := f[strings.ToUpper(key)]; ok {
+		return k
+	}
+	return key
+}
+
+// resolve returns the spelling of key already used in values or the
+// process environment, ignoring case, and records it for later keys.
+func (f keyFold) resolve(key string, values map[string]string) string {
+	if _, ok := values[key]; !ok {
+		if _, ok := os.LookupEnv(key); !ok {
+			key = f.spelling(key)
+		}
+	}
+	f.add(key)
+	return key
+}
+
+// Unused returns, sorted, the keys of loaded (e.g. LoadFiles' result) that
+// no getter, Unmarshal, Require or Environment lookup has read so far,
+// helping prune dead config.
+func Unused(loaded map[string]string) []string {
+	var out []string
+	for k := range loaded {
+		if _, ok := accessed.Load(k); !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ResetAccessed forgets all recorded reads.
+func ResetAccessed() { accessed.Clear() }
@@ -0,0 +1,93 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotSet is returned (wrapped) by the E getters for unset keys.
+var ErrNotSet = errors.New("not set")
+
+// StringE returns the value of key, or an error wrapping ErrNotSet. An empty
+// but set value is returned as is.
+func StringE(key string) (string, error) {
+	v, ok := lookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("env %s: %w", key, ErrNotSet)
+	}
+	return v, nil
+}
+
+// IntE is Int returning an error for unset or malformed values instead of
+// falling back or panicking.
+func IntE(key string) (int, error) {
+	v, err := StringE(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := parseInt(v, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("env %s: invalid int %q", key, v)
+	}
+	return int(i), nil
+}
+
+// BoolE is Bool returning an error for unset or malformed values.
+func BoolE(key string) (bool, error) {
+	v, err := StringE(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return false, fmt.Errorf("env %s: invalid bool %q", key, v)
+	}
+	return b, nil
+}
+
+// DurationE is Duration returning an error for unset or malformed values.
+func DurationE(key string) (time.Duration, error) {
+	v, err := StringE(key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("env %s: invalid duration %q", key, v)
+	}
+	return d, nil
+}
+
+// Duration reads a time.ParseDuration value such as "1m30s". Invalid values
+// fall back to def, or panic without one.
+func Duration(key string, def ...time.Duration) time.Duration {
+	d, err := DurationE(key)
+	if err == nil {
+		return d
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	if !errors.Is(err, ErrNotSet) {
+		v, _ := lookupEnv(key)
+		panic("invalid duration env " + key + ": " + v)
+	}
+	panic("missing env: " + key)
+}
+
+// JSON decodes the JSON value of key into target, typically a pointer to a
+// struct or map. Unset keys return an error wrapping ErrNotSet.
+func JSON(key string, target any) error {
+	v, err := StringE(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(v), target); err != nil {
+		return fmt.Errorf("env %s: invalid JSON: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Compare reports keys added to, removed from, or changed between two
+// snapshots. added and changed hold the new values; removed the old ones.
+func Compare(old, new map[string]string) (added, removed, changed map[string]string) {
+	added = map[string]string{}
+	removed = map[string]string{}
+	changed = map[string]string{}
+	for k, nv := range new {
+		ov, ok := old[k]
+		switch {
+		case !ok:
+			added[k] = nv
+		case ov != nv:
+			changed[k] = nv
+		}
+	}
+	for k, ov := range old {
+		if _, ok := new[k]; !ok {
+			removed[k] = ov
+		}
+	}
+	return added, removed, changed
+}
+
+// Change is a value that differs between two env files.
+type Change struct {
+	Old, New string
+}
+
+// DiffOptions controls Diff.
+type DiffOptions struct {
+	// Mask reports keys whose values are replaced by "****" in the result,
+	// e.g. SecretKey. Masked values still count as changed when they differ.
+	Mask func(key string) bool
+}
+
+// Diff parses two .env files and reports keys added in b, removed from a,
+// and changed between them. Values are compared as written: ${VAR}
+// references are not expanded, so the process environment neither affects
+// the result nor is modified.
+func Diff(a, b string, opts *DiffOptions) (added, removed map[string]string, changed map[string]Change, err error) {
+	av, err := parseFileValues(a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bv, err := parseFileValues(b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	added, removed, ch := Compare(av, bv)
+
+	mask := func(k, v string) string {
+		if opts != nil && opts.Mask != nil && opts.Mask(k) && v != "" {
+			return "****"
+		}
+		return v
+	}
+	changed = make(map[string]Change, len(ch))
+	for k := range ch {
+		changed[k] = Change{Old: mask(k, av[k]), New: mask(k, bv[k])}
+	}
+	for k, v := range added {
+		added[k] = mask(k, v)
+	}
+	for k, v := range removed {
+		removed[k] = mask(k, v)
+	}
+	return added, removed, changed, nil
+}
+
+func parseFileValues(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	opts := &Options{InlineComments: true}
+	es, err := parse(f, opts)
+	var values map[string]string
+	if err == nil {
+		values, _, _, err = resolveEntries(es, opts)
+	}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.File = path
+	}
+	return values, err
+}
+
+var secretWords = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "PRIVATE", "CREDENTIAL", "API_KEY", "APIKEY", "ACCESS_KEY"}
+
+// SecretKey reports whether key looks like it holds a secret, judging by
+// words such as SECRET, PASSWORD, TOKEN or API_KEY in its name.
+func SecretKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, w := range secretWords {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	return false
+}
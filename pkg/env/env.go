@@ -1,15 +1,13 @@
 package env
 
 import (
-	"bufio"
-	"fmt"
-	"os"
+	"math"
 	"strconv"
 	"strings"
 )
 
 func String(key string, def ...string) string {
-	v, ok := os.LookupEnv(key)
+	v, ok := lookupEnv(key)
 	if ok && strings.TrimSpace(v) != "" {
 		return v
 	}
@@ -23,14 +21,36 @@ func Get(key string, def ...string) string {
 	return String(key, def...)
 }
 
+// Int reads a decimal or 0x/0o/0b-prefixed integer. Invalid values fall back
+// to def, or panic without one.
 func Int(key string, def ...int) int {
-	if v, ok := os.LookupEnv(key); ok {
-		i, err := strconv.Atoi(v)
-		if err != nil {
+	if v, ok := lookupEnv(key); ok {
+		i, err := parseInt(v, strconv.IntSize)
+		if err == nil {
+			return int(i)
+		}
+		if len(def) == 0 {
 			panic("invalid int env " + key + ": " + v)
 		}
+	}
 
-		return i
+	if len(def) > 0 {
+		return def[0]
+	}
+
+	panic("missing env: " + key)
+}
+
+// Int64 is Int for int64 values.
+func Int64(key string, def ...int64) int64 {
+	if v, ok := lookupEnv(key); ok {
+		i, err := parseInt(v, 64)
+		if err == nil {
+			return i
+		}
+		if len(def) == 0 {
+			panic("invalid int env " + key + ": " + v)
+		}
 	}
 
 	if len(def) > 0 {
@@ -40,8 +60,28 @@ func Int(key string, def ...int) int {
 	panic("missing env: " + key)
 }
 
+// parseInt accepts Go-style 0x, 0o and 0b prefixes. Other values are parsed
+// as base 10, so "010" stays ten rather than octal.
+func parseInt(s string, bits int) (int64, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimLeft(s, "+-")
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return strconv.ParseInt(s, 0, bits)
+	}
+	return strconv.ParseInt(s, 10, bits)
+}
+
+// parseUint is parseInt for unsigned values; a leading '+' is allowed.
+func parseUint(s string, bits int) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "+")
+	if len(s) > 2 && s[0] == '0' && strings.ContainsRune("xXoObB", rune(s[1])) {
+		return strconv.ParseUint(s, 0, bits)
+	}
+	return strconv.ParseUint(s, 10, bits)
+}
+
 func Bool(key string, def ...bool) bool {
-	if v, ok := os.LookupEnv(key); ok {
+	if v, ok := lookupEnv(key); ok {
 		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
 			return b
 		}
@@ -52,37 +92,232 @@ func Bool(key string, def ...bool) bool {
 	panic("missing env: " + key)
 }
 
-func Load(path string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+// Truthy is a lenient flag getter for DEBUG=anything style switches. Unlike
+// Bool it never fails: an unset or blank value returns def; "false", "0",
+// "no" and "off" (case-insensitive, surrounding space ignored) are false; any
+// other value is true.
+func Truthy(key string, def bool) bool {
+	v, _ := lookupEnv(key)
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return def
+	}
+	switch strings.ToLower(v) {
+	case "false", "0", "no", "off":
+		return false
+	}
+	return true
+}
+
+// StringsEscaped splits the value on sep, where a backslash before sep keeps
+// it literal: a\,b,c with sep "," gives ["a,b" "c"]. Other backslashes,
+// including a trailing one, are kept as is. An unset or empty value
+// returns def.
+func StringsEscaped(key, sep string, def []string) []string {
+	v, _ := lookupEnv(key)
+	if v == "" || sep == "" {
+		return def
+	}
+	var out []string
+	var cur strings.Builder
+	for i := 0; i < len(v); {
+		switch {
+		case v[i] == '\\' && strings.HasPrefix(v[i+1:], sep):
+			cur.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(v[i:], sep):
+			out = append(out, cur.String())
+			cur.Reset()
+			i += len(sep)
+		default:
+			cur.WriteByte(v[i])
+			i++
 		}
-		return err
 	}
-	defer f.Close()
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	return append(out, cur.String())
+}
+
+// Map parses a list such as "api:100,web:50" (sep ",", kvSep ":") into a
+// map, trimming space around keys and values; later duplicates win. An
+// unset or blank value, or any entry without kvSep or with an empty key,
+// returns def.
+func Map(key, sep, kvSep string, def map[string]string) map[string]string {
+	v, _ := lookupEnv(key)
+	m, ok := parseMap(v, sep, kvSep)
+	if !ok {
+		return def
+	}
+	return m
+}
+
+// MapInt is Map with integer values, parsed like Int. Any invalid value
+// returns def.
+func MapInt(key, sep, kvSep string, def map[string]int) map[string]int {
+	v, _ := lookupEnv(key)
+	m, ok := parseMap(v, sep, kvSep)
+	if !ok {
+		return def
+	}
+	out := make(map[string]int, len(m))
+	for k, s := range m {
+		i, err := parseInt(s, strconv.IntSize)
+		if err != nil {
+			return def
 		}
-		i := strings.IndexByte(line, '=')
-		if i <= 0 {
+		out[k] = int(i)
+	}
+	return out
+}
+
+func parseMap(v, sep, kvSep string) (map[string]string, bool) {
+	if strings.TrimSpace(v) == "" || sep == "" || kvSep == "" {
+		return nil, false
+	}
+	out := map[string]string{}
+	for _, e := range strings.Split(v, sep) {
+		if strings.TrimSpace(e) == "" {
 			continue
 		}
-		key := strings.TrimSpace(line[:i])
-		val := strings.TrimSpace(line[i+1:])
-		val = strings.Trim(val, `"'`)
-		if key == "" {
-			continue
+		k, val, ok := strings.Cut(e, kvSep)
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, false
 		}
-		if _, set := os.LookupEnv(key); set {
-			continue
+		out[k] = strings.TrimSpace(val)
+	}
+	return out, true
+}
+
+// FeatureSet parses a comma list of flags such as "auth,billing,-beta".
+// A bare (or "+"-prefixed) name enables a flag, a "-" prefix disables it, and
+// later entries win. An unset key yields an empty set.
+func FeatureSet(key string) map[string]bool {
+	out := map[string]bool{}
+	v, _ := lookupEnv(key)
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		on := true
+		switch {
+		case strings.HasPrefix(f, "-"):
+			on = false
+			f = f[1:]
+		case strings.HasPrefix(f, "+"):
+			f = f[1:]
+		}
+		if f = strings.TrimSpace(f); f != "" {
+			out[f] = on
+		}
+	}
+	return out
+}
+
+// Count reads a count with an optional decimal suffix: K (1e3), M (1e6) or
+// B/G (1e9), e.g. "10K" or "1.5M". Invalid values fall back to def, or
+// panic without one.
+func Count(key string, def ...int64) int64 {
+	if v, ok := lookupEnv(key); ok {
+		if n, err := parseCount(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+var countUnits = map[byte]float64{'K': 1e3, 'M': 1e6, 'B': 1e9, 'G': 1e9}
+
+func parseCount(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if s == "" {
+		return 0, strconv.ErrSyntax
+	}
+	mult, ok := countUnits[strings.ToUpper(s[len(s)-1:])[0]]
+	if !ok {
+		return 0, strconv.ErrSyntax
+	}
+	f, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	n := f * mult
+	if n != math.Trunc(n) || math.Abs(n) > math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(n), nil
+}
+
+// Float64 reads a floating-point value. Invalid values fall back to def, or
+// panic without one.
+func Float64(key string, def ...float64) float64 {
+	if v, ok := lookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+// Uint reads a non-negative integer, accepting 0x/0o/0b prefixes like Int.
+// Invalid values fall back to def, or panic without one.
+func Uint(key string, def ...uint) uint {
+	if v, ok := lookupEnv(key); ok {
+		if u, err := parseUint(v, strconv.IntSize); err == nil {
+			return uint(u)
 		}
-		if err := os.Setenv(key, val); err != nil {
-			return fmt.Errorf("setenv %q: %w", key, err)
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+// Bytes reads a size such as "512", "10MB", "512KiB" or "1.5 GB". Decimal
+// units (K/KB, M/MB, G/GB, T/TB) are powers of 1000, binary ones (KiB,
+// MiB, GiB, TiB) powers of 1024; case is ignored. Invalid values fall back
+// to def, or panic without one.
+func Bytes(key string, def ...int64) int64 {
+	if v, ok := lookupEnv(key); ok {
+		if n, err := parseBytes(v); err == nil {
+			return n
 		}
 	}
-	return sc.Err()
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+var byteUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "tib": 1 << 40,
+}
+
+func parseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if i < 0 {
+		i = len(s)
+	}
+	mult, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok || i == 0 {
+		return 0, strconv.ErrSyntax
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	n := math.Round(f * mult)
+	if n > math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(n), nil
 }
@@ -0,0 +1,1486 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func writeEnv(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// unsetenv clears keys for the duration of the test.
+func unsetenv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+}
+
+func TestLoadProviders(t *testing.T) {
+	unsetenv(t, "PROV_DSN", "PROV_USER")
+	t.Setenv("PROV_HOST", "os-host")
+
+	p := writeEnv(t, "PROV_DSN=postgres://${PROV_USER}@${PROV_HOST}/db\n")
+	opts := &Options{Expand: true, Providers: []Provider{
+		MapProvider{"PROV_USER": "svc", "PROV_HOST": "provider-host"},
+	}}
+	values, err := LoadFiles([]string{p}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "postgres://svc@os-host/db"
+	if values["PROV_DSN"] != want || os.Getenv("PROV_DSN") != want {
+		t.Fatalf("got %q / %q, want %q", values["PROV_DSN"], os.Getenv("PROV_DSN"), want)
+	}
+
+	// an Environment falls back to providers for keys neither the files nor
+	// the OS set; the package getters do not
+	e, err := LoadEnvironment([]string{p}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String("PROV_USER"); got != "svc" {
+		t.Fatalf("PROV_USER = %q, want provider value", got)
+	}
+	if got := e.String("PROV_HOST"); got != "os-host" {
+		t.Fatalf("PROV_HOST = %q, want OS value", got)
+	}
+	if got := String("PROV_USER", "none"); got != "none" {
+		t.Fatalf("package getter PROV_USER = %q, providers must not leak", got)
+	}
+
+	boom := errors.New("vault down")
+	e, err = LoadEnvironment([]string{p}, &Options{Providers: []Provider{failingProvider{boom}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.String("PROV_USER", "def"); got != "def" || !errors.Is(e.Err(), boom) {
+		t.Fatalf("PROV_USER = %q, Err = %v", got, e.Err())
+	}
+	if _, _, err := e.LookupE("PROV_USER"); !errors.Is(err, boom) {
+		t.Fatalf("LookupE err = %v", err)
+	}
+}
+
+type failingProvider struct{ err error }
+
+func (f failingProvider) Lookup(string) (string, bool, error) { return "", false, f.err }
+
+func TestWindowsExpand(t *testing.T) {
+	unsetenv(t, "WIN_A", "WIN_B", "WIN_MISSING")
+	t.Setenv("WIN_HOME", `C:\Users\sam`)
+
+	p := writeEnv(t, "WIN_A=%WIN_HOME%\\app;%WIN_MISSING%;100%%\nWIN_B=${WIN_HOME}/x\n")
+	values, err := LoadFiles([]string{p}, &Options{Expand: true, WindowsExpand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := values["WIN_A"], `C:\Users\sam\app;%WIN_MISSING%;100%`; got != want {
+		t.Fatalf("WIN_A = %q, want %q", got, want)
+	}
+	if got, want := values["WIN_B"], `C:\Users\sam/x`; got != want {
+		t.Fatalf("WIN_B = %q, want %q", got, want)
+	}
+
+	unsetenv(t, "WIN_DSN")
+	p = writeEnv(t, "WIN_DSN=postgres://u:p%40ss%40host/db%2Fx?q=%AB%CD\n")
+	values, err = LoadFiles([]string{p}, &Options{Expand: true, WindowsExpand: true})
+	if want := "postgres://u:p%40ss%40host/db%2Fx?q=%AB%CD"; err != nil || values["WIN_DSN"] != want {
+		t.Fatalf("WIN_DSN = %q, %v; want %q", values["WIN_DSN"], err, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := map[string]string{"A": "1", "B": "2", "C": "3"}
+	cur := map[string]string{"A": "1", "B": "20", "D": "4"}
+
+	added, removed, changed := Compare(old, cur)
+	if !maps.Equal(added, map[string]string{"D": "4"}) {
+		t.Fatalf("added = %v", added)
+	}
+	if !maps.Equal(removed, map[string]string{"C": "3"}) {
+		t.Fatalf("removed = %v", removed)
+	}
+	if !maps.Equal(changed, map[string]string{"B": "20"}) {
+		t.Fatalf("changed = %v", changed)
+	}
+
+	added, removed, changed = Compare(old, maps.Clone(old))
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Fatalf("identical maps diffed: %v %v %v", added, removed, changed)
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	t.Setenv("APP_NAME", "demo")
+	t.Setenv("APP_GREETING", "hello world # not a comment")
+	t.Setenv("APP_PRICE", "$5")
+	t.Setenv("OTHER_KEY", "x")
+
+	p := filepath.Join(t.TempDir(), "out.env")
+	if err := ExportToFile(p, "APP_"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"APP_NAME":     "demo",
+		"APP_GREETING": "hello world # not a comment",
+		"APP_PRICE":    "$5",
+	}
+	unsetenv(t, "APP_NAME", "APP_GREETING", "APP_PRICE")
+	if err := Load(p); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range want {
+		if os.Getenv(k) != v {
+			t.Fatalf("Load %s = %q, want %q", k, os.Getenv(k), v)
+		}
+	}
+	unsetenv(t, "APP_NAME", "APP_GREETING", "APP_PRICE")
+
+	values, err := LoadFiles([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(values, want) {
+		t.Fatalf("round trip = %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if os.Getenv(k) != v {
+			t.Fatalf("%s = %q, want %q", k, os.Getenv(k), v)
+		}
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	unsetenv(t, "NORM_PORT", "NORM_HOST")
+	p := writeEnv(t, "norm_port=8080\nNorm_Host=localhost\n")
+	norm := func(k string) string { return strings.ToUpper(strings.TrimSpace(k)) }
+	if _, err := LoadFiles([]string{p}, &Options{NormalizeKey: norm}); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("NORM_PORT") != "8080" || os.Getenv("NORM_HOST") != "localhost" {
+		t.Fatalf("normalized keys not set: %q %q", os.Getenv("NORM_PORT"), os.Getenv("NORM_HOST"))
+	}
+
+	unsetenv(t, "NORM_TRIM")
+	trim := func(k string) string { return strings.Trim(k, "_") }
+	p = writeEnv(t, "_NORM_TRIM_=1\n")
+	if _, err := LoadFiles([]string{p}, &Options{NormalizeKey: trim}); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("NORM_TRIM") != "1" {
+		t.Fatalf("NORM_TRIM = %q", os.Getenv("NORM_TRIM"))
+	}
+}
+
+func TestLoadLiteral(t *testing.T) {
+	unsetenv(t, "LIT_PW", "LIT_NOTE", "LIT_Q", "LIT_ESC", "LIT_SQ", "LIT_STRAY")
+	t.Setenv("LIT_SET", "os")
+	p := writeEnv(t, "LIT_PW=pa$word\nLIT_NOTE=a # b\nLIT_Q=\"quoted\"\nLIT_ESC=\"a\\nb\"\nLIT_SET=file\n"+
+		"LIT_SQ='x\"'\nLIT_STRAY=abc\"\n")
+	if err := Load(p); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"LIT_PW": "pa$word", "LIT_NOTE": "a # b", "LIT_Q": "quoted", "LIT_ESC": "a\nb", "LIT_SET": "os",
+		"LIT_SQ": `x"`, "LIT_STRAY": "abc",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Fatalf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	values, err := LoadFiles([]string{p}, &Options{Overwrite: true})
+	if err != nil || values["LIT_PW"] != "pa$word" || values["LIT_NOTE"] != "a # b" {
+		t.Fatalf("LoadFiles without opt-ins = %q, %v", values, err)
+	}
+	values, err = LoadFiles([]string{p}, &Options{Overwrite: true, Expand: true, InlineComments: true})
+	if err != nil || values["LIT_PW"] != "pa" || values["LIT_NOTE"] != "a" {
+		t.Fatalf("LoadFiles with opt-ins = %q, %v", values, err)
+	}
+}
+
+func TestMustLoad(t *testing.T) {
+	unsetenv(t, "MUST_A", "MUST_B", "MUST_C", "MUST_D")
+	p := writeEnv(t, "MUST_A=1\nMUST_B=2\n")
+
+	MustLoad([]string{p}, nil, "MUST_A", "MUST_B")
+
+	defer func() {
+		msg, _ := recover().(string)
+		if msg != "missing env: MUST_C, MUST_D" {
+			t.Fatalf("unexpected panic %q", msg)
+		}
+	}()
+	MustLoad([]string{p}, nil, "MUST_A", "MUST_C", "MUST_D")
+}
+
+func TestExpandNestedDefaults(t *testing.T) {
+	vars := map[string]string{"SET": "yes", "EMPTY": ""}
+	x := expander{lookup: MapProvider(vars).Lookup}
+
+	cases := map[string]string{
+		"${SET:-no}":                         "yes",
+		"${EMPTY:-fallback}":                 "fallback",
+		"${A:-${SET:-x}}":                    "yes",
+		"${A:-${B:-fallback}}":               "fallback",
+		"${A:-${B:-${C:-deep}}}/tail":        "deep/tail",
+		"pre-${A:-${B:-${SET}-suffix}}-post": "pre-yes-suffix-post",
+	}
+	for in, want := range cases {
+		got, err := x.expand(in)
+		if err != nil || got != want {
+			t.Fatalf("expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := x.expand("${A:-${B}"); err == nil {
+		t.Fatal("expected error for unbalanced reference")
+	}
+}
+
+func TestFeatureSet(t *testing.T) {
+	t.Setenv("FEATURES", "auth, billing,-beta,beta2,-beta2")
+	want := map[string]bool{"auth": true, "billing": true, "beta": false, "beta2": false}
+	if got := FeatureSet("FEATURES"); !maps.Equal(got, want) {
+		t.Fatalf("FeatureSet = %v, want %v", got, want)
+	}
+	unsetenv(t, "NO_FEATURES")
+	if got := FeatureSet("NO_FEATURES"); len(got) != 0 {
+		t.Fatalf("unset key = %v", got)
+	}
+}
+
+func TestStrictEscapes(t *testing.T) {
+	unsetenv(t, "ESC_OK", "ESC_BAD")
+	p := writeEnv(t, "ESC_OK=\"a\\nb\"\nESC_BAD=\"x\\qy\"\n")
+
+	_, err := LoadFiles([]string{p}, &Options{StrictEscapes: true})
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 2 || !strings.Contains(err.Error(), `invalid escape "\\q"`) {
+		t.Fatalf("expected line 2 escape error, got %v", err)
+	}
+
+	values, err := LoadFiles([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["ESC_OK"] != "a\nb" || values["ESC_BAD"] != `x\qy` {
+		t.Fatalf("lenient values = %q", values)
+	}
+}
+
+func TestDottedKeys(t *testing.T) {
+	unsetenv(t, "my.service.port", "log4j.level", "my.service.url")
+	p := writeEnv(t, "my.service.port=8080\nlog4j.level=DEBUG\nmy.service.url=http://localhost:${my.service.port}\n")
+	values, err := LoadFiles([]string{p}, &Options{Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if String("my.service.port") != "8080" || String("log4j.level") != "DEBUG" {
+		t.Fatalf("dotted keys not readable: %v", values)
+	}
+	if got := values["my.service.url"]; got != "http://localhost:8080" {
+		t.Fatalf("expanded = %q", got)
+	}
+
+	back, err := LoadFiles([]string{writeEnv(t, Marshal(values))}, nil)
+	if err != nil || !maps.Equal(back, values) {
+		t.Fatalf("round trip = %v, %v", back, err)
+	}
+}
+
+func TestExpandFilters(t *testing.T) {
+	vars := map[string]string{"NAME": " Sam ", "BIN": "/usr/local/bin/app"}
+	x := expander{lookup: MapProvider(vars).Lookup}
+
+	cases := map[string]string{
+		"${NAME|upper}":                  " SAM ",
+		"${NAME|trim|upper}":             "SAM",
+		"${BIN|basename}":                "app",
+		"${MISSING|default:foo}":         "foo",
+		"${MISSING|default:Foo|lower}":   "foo",
+		"${MISSING:-${NAME|trim}|upper}": "SAM",
+	}
+	for in, want := range cases {
+		got, err := x.expand(in)
+		if err != nil || got != want {
+			t.Fatalf("expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := x.expand("${NAME|shout}"); err == nil || !strings.Contains(err.Error(), `unknown filter "shout"`) {
+		t.Fatalf("expected unknown filter error, got %v", err)
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	unsetenv(t, "DEF_BASE", "DEF_LOCAL")
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(".env", []byte("DEF_BASE=env\nDEF_LOCAL=env\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(".env.local", []byte("DEF_LOCAL=local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := LoadDefault(nil)
+	if err != nil || values["DEF_LOCAL"] != "env" {
+		t.Fatalf("built-in default = %v, %v", values, err)
+	}
+
+	values, err = LoadDefault(&Options{DefaultFiles: []string{".env", ".env.local"}})
+	if err != nil || values["DEF_BASE"] != "env" || values["DEF_LOCAL"] != "local" {
+		t.Fatalf("custom defaults = %v, %v", values, err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	cases := map[string]int64{"10K": 10_000, "1.5M": 1_500_000, "2b": 2_000_000_000, "42": 42}
+	for v, want := range cases {
+		t.Setenv("COUNT", v)
+		if got := Count("COUNT", -1); got != want {
+			t.Fatalf("Count(%q) = %d, want %d", v, got, want)
+		}
+	}
+	for _, v := range []string{"ten", "10X", "1.0001K"} {
+		t.Setenv("COUNT", v)
+		if got := Count("COUNT", 7); got != 7 {
+			t.Fatalf("Count(%q) = %d, want default", v, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("RESOLVE_UNTOUCHED", "os")
+	in := map[string]string{
+		"URL":  "http://${HOST}:${PORT}/${PATH}",
+		"HOST": "${DOMAIN}",
+		"PORT": "${RESOLVE_UNTOUCHED:-8080}",
+	}
+	extra := MapProvider{"DOMAIN": "example.com"}
+	out, err := Resolve(in, func(k string) (string, bool) {
+		v, ok, _ := extra.Lookup(k)
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out["URL"], "http://example.com:8080/"; got != want {
+		t.Fatalf("URL = %q, want %q", got, want)
+	}
+	if in["URL"] != "http://${HOST}:${PORT}/${PATH}" {
+		t.Fatal("input map was mutated")
+	}
+
+	_, err = Resolve(map[string]string{"A": "${B}", "B": "x${A}"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestAccumulateKeys(t *testing.T) {
+	unsetenv(t, "ACC_HEADER", "ACC_MODE")
+	p := writeEnv(t, "ACC_HEADER=a\nACC_MODE=one\nACC_HEADER=b\nACC_MODE=two\nACC_HEADER=c\n")
+	values, err := LoadFiles([]string{p}, &Options{AccumulateKeys: []string{"ACC_HEADER"}, AccumulateSep: "|"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(os.Getenv("ACC_HEADER"), "|"); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("ACC_HEADER = %q", got)
+	}
+	if values["ACC_MODE"] != "two" {
+		t.Fatalf("ACC_MODE = %q, want last value", values["ACC_MODE"])
+	}
+}
+
+func TestIntLiterals(t *testing.T) {
+	cases := map[string]int64{"0xFF": 255, "0o755": 0o755, "0b1010": 10, "42": 42, "010": 10, "-0x10": -16}
+	for v, want := range cases {
+		t.Setenv("INT_LIT", v)
+		if got := Int64("INT_LIT", -1); got != want {
+			t.Fatalf("Int64(%q) = %d, want %d", v, got, want)
+		}
+		if got := Int("INT_LIT", -1); int64(got) != want {
+			t.Fatalf("Int(%q) = %d, want %d", v, got, want)
+		}
+	}
+	t.Setenv("INT_LIT", "0xZZ")
+	if got := Int("INT_LIT", 7); got != 7 {
+		t.Fatalf("invalid literal = %d, want default", got)
+	}
+
+	// unsigned getters and fields treat leading zeros as decimal too
+	for v, want := range map[string]uint64{"010": 10, "08": 8, "0x10": 16, "+7": 7} {
+		t.Setenv("INT_LIT", v)
+		if got := Uint("INT_LIT", 0); uint64(got) != want {
+			t.Fatalf("Uint(%q) = %d, want %d", v, got, want)
+		}
+		var cfg struct {
+			N uint16 `env:"INT_LIT"`
+		}
+		if err := Unmarshal(&cfg); err != nil || uint64(cfg.N) != want {
+			t.Fatalf("Unmarshal uint16 %q = %d, %v; want %d", v, cfg.N, err, want)
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	cases := []struct {
+		val  string
+		def  bool
+		want bool
+	}{
+		{"1", false, true},
+		{"yes", false, true},
+		{"anything", false, true},
+		{"0", true, false},
+		{"OFF", true, false},
+		{"", true, true},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		t.Setenv("DEBUG", c.val)
+		if got := Truthy("DEBUG", c.def); got != c.want {
+			t.Fatalf("Truthy(%q, %v) = %v", c.val, c.def, got)
+		}
+	}
+	unsetenv(t, "DEBUG")
+	if !Truthy("DEBUG", true) || Truthy("DEBUG", false) {
+		t.Fatal("unset should return def")
+	}
+}
+
+func TestLoadFilesResult(t *testing.T) {
+	unsetenv(t, "RES_NEW")
+	t.Setenv("RES_SET", "from-os")
+	p := writeEnv(t, "RES_SET=from-file\nRES_NEW=fresh\n")
+
+	res, err := LoadFilesResult([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Applied, []string{"RES_NEW"}) || !slices.Equal(res.Skipped, []string{"RES_SET"}) {
+		t.Fatalf("applied %v, skipped %v", res.Applied, res.Skipped)
+	}
+	if res.Values["RES_SET"] != "from-file" || os.Getenv("RES_SET") != "from-os" {
+		t.Fatalf("values %v, env %q", res.Values, os.Getenv("RES_SET"))
+	}
+}
+
+func TestShellAssignments(t *testing.T) {
+	unsetenv(t, "SH_A", "SH_B", "SH_C", "SH_D", "SH_E", "SH_F", "SH_G")
+	p := writeEnv(t, `declare -x SH_A=1
+typeset SH_B=two
+SH_C="x; y"; SH_D=4
+SH_E=a=1;b=2
+export SH_F=5;  typeset -x SH_G=6
+`)
+	values, err := LoadFiles([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"SH_A": "1", "SH_B": "two", "SH_C": "x; y", "SH_D": "4",
+		"SH_E": "a=1;b=2", "SH_F": "5", "SH_G": "6",
+	}
+	if !maps.Equal(values, want) {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestOnSet(t *testing.T) {
+	unsetenv(t, "HOOK_NEW")
+	t.Setenv("HOOK_OLD", "before")
+	p := writeEnv(t, "HOOK_NEW=a\nHOOK_OLD=b\n")
+
+	var got []string
+	onSet := func(k, v string, overwritten bool) {
+		got = append(got, fmt.Sprintf("%s=%s %v", k, v, overwritten))
+	}
+	if _, err := LoadFiles([]string{p}, &Options{OnSet: onSet}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"HOOK_NEW=a false"}) {
+		t.Fatalf("without Overwrite: %q", got)
+	}
+
+	got = nil
+	if _, err := LoadFiles([]string{p}, &Options{OnSet: onSet, Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"HOOK_NEW=a true", "HOOK_OLD=b true"}) {
+		t.Fatalf("with Overwrite: %q", got)
+	}
+}
+
+func TestStringsEscaped(t *testing.T) {
+	cases := map[string][]string{
+		`a\,b,c`:  {"a,b", "c"},
+		`a,b\`:    {"a", `b\`},
+		`a,b,c`:   {"a", "b", "c"},
+		`x\y,z\,`: {`x\y`, "z,"},
+	}
+	for v, want := range cases {
+		t.Setenv("LIST", v)
+		if got := StringsEscaped("LIST", ",", nil); !slices.Equal(got, want) {
+			t.Fatalf("StringsEscaped(%q) = %q, want %q", v, got, want)
+		}
+	}
+	t.Setenv("LIST", "")
+	if got := StringsEscaped("LIST", ",", []string{"d"}); !slices.Equal(got, []string{"d"}) {
+		t.Fatalf("empty = %q, want default", got)
+	}
+}
+
+func TestLoadTyped(t *testing.T) {
+	unsetenv(t, "TY_PORT", "TY_ON", "TY_NAME", "TY_CONF", "TY_RATE", "TY_STR")
+	p := writeEnv(t, `TY_PORT=8080
+TY_ON=true
+TY_NAME="8080"
+TY_CONF={"a":1,"b":["x"]}
+TY_RATE=0.5
+TY_STR=hello
+`)
+	values, err := LoadTyped([]string{p}, &Options{InferTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := values["TY_PORT"].(int); !ok || v != 8080 {
+		t.Fatalf("TY_PORT = %#v", values["TY_PORT"])
+	}
+	if v, ok := values["TY_ON"].(bool); !ok || !v {
+		t.Fatalf("TY_ON = %#v", values["TY_ON"])
+	}
+	if v, ok := values["TY_NAME"].(string); !ok || v != "8080" {
+		t.Fatalf("TY_NAME = %#v", values["TY_NAME"])
+	}
+	if v, ok := values["TY_CONF"].(map[string]any); !ok || v["a"] != 1.0 {
+		t.Fatalf("TY_CONF = %#v", values["TY_CONF"])
+	}
+	if values["TY_RATE"] != 0.5 || values["TY_STR"] != "hello" {
+		t.Fatalf("TY_RATE = %#v, TY_STR = %#v", values["TY_RATE"], values["TY_STR"])
+	}
+	if os.Getenv("TY_PORT") != "8080" {
+		t.Fatal("process env not set")
+	}
+
+	values, err = LoadTyped([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["TY_PORT"] != "8080" {
+		t.Fatalf("without InferTypes TY_PORT = %#v", values["TY_PORT"])
+	}
+}
+
+func TestSections(t *testing.T) {
+	unsetenv(t, "SEC_MODE", "DATABASE_HOST", "DATABASE_PORT", "CACHE_HOST", "CACHE_PORT", "MY_APP_NAME")
+	p := writeEnv(t, `SEC_MODE=dev
+[database]
+HOST=db.local
+PORT=5432
+[cache]
+HOST=redis.local
+PORT=6379
+[my-app]
+NAME=demo
+`)
+	values, err := LoadFiles([]string{p}, &Options{Sections: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"SEC_MODE":      "dev",
+		"DATABASE_HOST": "db.local", "DATABASE_PORT": "5432",
+		"CACHE_HOST": "redis.local", "CACHE_PORT": "6379",
+		"MY_APP_NAME": "demo",
+	}
+	if !maps.Equal(values, want) {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	unsetenv(t, "APP_NAME", "APP_TAGS", "APP_DB_HOST", "APP_DB_PORT", "APP_TOKEN", "APP_KEY")
+	t.Setenv("APP_PORT", "0x1F90")
+	t.Setenv("APP_DEBUG", "true")
+	t.Setenv("APP_TIMEOUT", "1500ms")
+	t.Setenv("APP_RATIO", "0.5")
+	t.Setenv("APP_DB_HOST", "db.local")
+
+	type db struct {
+		Host string `env:"HOST,required"`
+		Port int    `env:"PORT,default=5432"`
+	}
+	var cfg struct {
+		Name    string        `env:"NAME,default=demo"`
+		Port    int           `env:"PORT"`
+		Debug   bool          `env:"DEBUG"`
+		Timeout time.Duration `env:"TIMEOUT"`
+		Ratio   float64       `env:"RATIO"`
+		Tags    []string      `env:"TAGS,default=a,b"`
+		DB      db            `env:"DB"`
+		Ignored string
+	}
+	if err := UnmarshalWithPrefix("APP_", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "demo" || cfg.Port != 8080 || !cfg.Debug || cfg.Timeout != 1500*time.Millisecond ||
+		cfg.Ratio != 0.5 || !slices.Equal(cfg.Tags, []string{"a", "b"}) ||
+		cfg.DB != (db{Host: "db.local", Port: 5432}) {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+
+	var req struct {
+		Token string `env:"TOKEN,required"`
+		Key   string `env:"KEY,required"`
+	}
+	err := UnmarshalWithPrefix("APP_", &req)
+	if err == nil || err.Error() != "missing env: APP_TOKEN, APP_KEY" {
+		t.Fatalf("err = %v", err)
+	}
+
+	t.Setenv("APP_PORT", "nope")
+	if err := UnmarshalWithPrefix("APP_", &cfg); err == nil || !strings.Contains(err.Error(), "APP_PORT") {
+		t.Fatalf("bad int err = %v", err)
+	}
+}
+
+func TestUnmarshalTextFields(t *testing.T) {
+	t.Setenv("TX_START", "2024-05-01T10:00:00Z")
+	t.Setenv("TX_URL", "https://example.com/api")
+	t.Setenv("TX_ADDRS", "10.0.0.1, ::1")
+	t.Setenv("TX_BAD", "yesterday")
+
+	var cfg struct {
+		Start time.Time `env:"START,required"`
+		URL   url.URL   `env:"URL"`
+		Addrs []net.IP  `env:"ADDRS"`
+	}
+	if err := UnmarshalWithPrefix("TX_", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Start.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || cfg.URL.Host != "example.com" ||
+		len(cfg.Addrs) != 2 || !cfg.Addrs[1].Equal(net.IPv6loopback) {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+
+	var bad struct {
+		Start time.Time `env:"TX_BAD"`
+	}
+	if err := Unmarshal(&bad); err == nil || !strings.Contains(err.Error(), "TX_BAD") {
+		t.Fatalf("bad time err = %v", err)
+	}
+	if got := GetAs("TX_START", time.Time{}); got.Year() != 2024 {
+		t.Fatalf("GetAs time = %v", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	unsetenv(t, "REQ_A", "REQ_C")
+	t.Setenv("REQ_B", "set")
+	t.Setenv("REQ_C", " ")
+	if err := Require("REQ_A", "REQ_B", "REQ_C"); err == nil || err.Error() != "missing env: REQ_A, REQ_C" {
+		t.Fatalf("err = %v", err)
+	}
+	if err := Require("REQ_B"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	values := map[string]string{
+		"W_PLAIN": "x", "W_SPACE": "a b", "W_QUOTE": `it's "q"`, "W_NL": "l1\nl2",
+		"W_DOLLAR": "it's $HOME", "W_REF": "${W_PLAIN}\n\\$x", "W_SQ": `x"`,
+	}
+	p := filepath.Join(t.TempDir(), "out.env")
+	if err := Write(p, values, &WriteOptions{Export: true, Header: "generated\ndo not edit"}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(p)
+	if !strings.HasPrefix(string(b), "# generated\n# do not edit\nexport W_DOLLAR=") {
+		t.Fatalf("file = %q", b)
+	}
+	if info, _ := os.Stat(p); info.Mode().Perm() != 0o600 {
+		t.Fatalf("perm = %v", info.Mode().Perm())
+	}
+
+	unsetenv(t, "W_PLAIN", "W_SPACE", "W_QUOTE", "W_NL", "W_DOLLAR", "W_REF", "W_SQ")
+	got, err := LoadFiles([]string{p}, &Options{Overwrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(got, values) {
+		t.Fatalf("round trip = %q", got)
+	}
+
+	// escaped $ must survive expansion on re-read
+	got, err = LoadFiles([]string{p}, &Options{Overwrite: true, Expand: true})
+	if err != nil || !maps.Equal(got, values) {
+		t.Fatalf("expanded round trip = %q, %v", got, err)
+	}
+	if got, err = ParseString(string(b)); err != nil || !maps.Equal(got, values) {
+		t.Fatalf("ParseString round trip = %q, %v", got, err)
+	}
+
+	unsetenv(t, "W_PLAIN", "W_SPACE", "W_QUOTE", "W_NL", "W_DOLLAR", "W_REF", "W_SQ")
+	if err := Load(p); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		if got := os.Getenv(k); got != v {
+			t.Fatalf("Load round trip %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestMultilineValues(t *testing.T) {
+	unsetenv(t, "ML_KEY", "ML_AFTER", "ML_ESC")
+	p := writeEnv(t, `ML_KEY="-----BEGIN KEY-----
+abc\"def
+-----END KEY-----"
+ML_ESC="one\ntwo"
+ML_AFTER=ok
+`)
+	values, err := LoadFiles([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-----BEGIN KEY-----\nabc\"def\n-----END KEY-----"; values["ML_KEY"] != want {
+		t.Fatalf("ML_KEY = %q", values["ML_KEY"])
+	}
+	if values["ML_ESC"] != "one\ntwo" || values["ML_AFTER"] != "ok" {
+		t.Fatalf("values = %q", values)
+	}
+
+	p = writeEnv(t, "OK=1\nML_BAD=\"never closed\nX=1\n")
+	var pe *ParseError
+	if _, err := LoadFiles([]string{p}, nil); !errors.As(err, &pe) || pe.Line != 2 {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExpandRequired(t *testing.T) {
+	unsetenv(t, "RQ_SECRET", "RQ_URL", "RQ_PORT")
+	p := writeEnv(t, "RQ_PORT=${RQ_UNSET_PORT:-8080}\nRQ_URL=http://x:${RQ_PORT:?port needed}\n")
+	values, err := LoadFiles([]string{p}, &Options{Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["RQ_URL"] != "http://x:8080" {
+		t.Fatalf("RQ_URL = %q", values["RQ_URL"])
+	}
+
+	p = writeEnv(t, "RQ_SECRET=${RQ_UNSET_SECRET:?must be set}\n")
+	if _, err := LoadFiles([]string{p}, &Options{Expand: true}); err == nil || !strings.Contains(err.Error(), "RQ_UNSET_SECRET: must be set") {
+		t.Fatalf("err = %v", err)
+	}
+	p = writeEnv(t, "RQ_SECRET=${RQ_UNSET_SECRET:?}\n")
+	if _, err := LoadFiles([]string{p}, &Options{Expand: true}); err == nil || !strings.Contains(err.Error(), "parameter null or not set") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	old := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = old })
+
+	unsetenv(t, "WT_A", "WT_B", "WT_C")
+	t.Setenv("WT_OS", "from-os")
+	p := writeEnv(t, "WT_A=1\nWT_B=2\nWT_OS=file\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Watch(ctx, []string{p}, nil, nil); err == nil {
+		t.Fatal("expected error for nil fn")
+	}
+	if _, ok := os.LookupEnv("WT_A"); ok {
+		t.Fatal("nil fn should not load the files")
+	}
+	deltas := make(chan map[string]string, 4)
+	if err := Watch(ctx, []string{p}, nil, func(d map[string]string) { deltas <- d }); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("WT_A") != "1" {
+		t.Fatal("initial load not applied")
+	}
+
+	// a different size guarantees the change is seen even on coarse mtimes
+	if err := os.WriteFile(p, []byte("WT_A=10\nWT_C=3\nWT_OS=file2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-deltas:
+		want := map[string]string{"WT_A": "10", "WT_B": "", "WT_C": "3", "WT_OS": "file2"}
+		if !maps.Equal(d, want) {
+			t.Fatalf("delta = %v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reload")
+	}
+	if os.Getenv("WT_A") != "10" || os.Getenv("WT_C") != "3" || os.Getenv("WT_OS") != "from-os" {
+		t.Fatalf("env after reload: A=%q C=%q OS=%q", os.Getenv("WT_A"), os.Getenv("WT_C"), os.Getenv("WT_OS"))
+	}
+}
+
+func TestLoadRemote(t *testing.T) {
+	unsetenv(t, "RM_HOST", "RM_PORT")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "RM_HOST=svc.local\nRM_PORT=${RM_PORT_OVERRIDE:-9000}\n")
+	}))
+	defer srv.Close()
+
+	opts := &RemoteOptions{Options: Options{Expand: true}, Header: http.Header{"Authorization": {"Bearer tok"}}}
+	res, err := LoadRemote(context.Background(), []string{srv.URL}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("RM_HOST") != "svc.local" || res.Values["RM_PORT"] != "9000" {
+		t.Fatalf("values = %v", res.Values)
+	}
+
+	if _, err := LoadRemote(context.Background(), []string{srv.URL}, nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("unauthorized err = %v", err)
+	}
+
+	unsetenv(t, "RM_BIG")
+	big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "RM_BIG="+strings.Repeat("x", maxRemoteSize)+"\n")
+	}))
+	defer big.Close()
+	if _, err := LoadRemote(context.Background(), []string{big.URL}, nil); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("oversized err = %v", err)
+	}
+	if _, set := os.LookupEnv("RM_BIG"); set {
+		t.Fatal("a truncated document must not be applied")
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	unsetenv(t, "MYAPP_MISSING", "MYAPP_DB_USER")
+	t.Setenv("PORT", "1")
+	t.Setenv("MYAPP_PORT", "8080")
+	t.Setenv("MYAPP_DB_HOST", "db")
+
+	app := WithPrefix("MYAPP_")
+	if app.Int("PORT") != 8080 || app.String("MISSING", "x") != "x" {
+		t.Fatalf("PORT = %d", app.Int("PORT"))
+	}
+	db := app.WithPrefix("DB_")
+	if db.String("HOST") != "db" || db.Key("HOST") != "MYAPP_DB_HOST" {
+		t.Fatalf("nested scope: %q", db.String("HOST"))
+	}
+	if err := db.Require("HOST", "USER"); err == nil || err.Error() != "missing env: MYAPP_DB_USER" {
+		t.Fatalf("Require err = %v", err)
+	}
+}
+
+func TestNumericGetters(t *testing.T) {
+	t.Setenv("NUM_F", "2.5")
+	t.Setenv("NUM_U", "0x10")
+	t.Setenv("NUM_NEG", "-1")
+	if Float64("NUM_F") != 2.5 || Uint("NUM_U") != 16 || Uint("NUM_NEG", 7) != 7 {
+		t.Fatalf("Float64 = %v, Uint = %v", Float64("NUM_F"), Uint("NUM_U"))
+	}
+
+	cases := map[string]int64{
+		"512": 512, "10MB": 10e6, "512KiB": 512 << 10, "1.5 GB": 1.5e9,
+		"2gib": 2 << 30, "1k": 1000, "3B": 3,
+	}
+	for v, want := range cases {
+		t.Setenv("MAX_UPLOAD", v)
+		if got := Bytes("MAX_UPLOAD", -1); got != want {
+			t.Fatalf("Bytes(%q) = %d, want %d", v, got, want)
+		}
+	}
+	for _, bad := range []string{"MB", "10XB", "1.2.3K", ""} {
+		t.Setenv("MAX_UPLOAD", bad)
+		if got := Bytes("MAX_UPLOAD", 10<<20); got != 10<<20 {
+			t.Fatalf("Bytes(%q) = %d, want default", bad, got)
+		}
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	t.Setenv("TG_URL", "https://api.example.com/v1")
+	t.Setenv("TG_BADURL", "not a url")
+	t.Setenv("TG_IP", "::1")
+	t.Setenv("TG_BADIP", "300.1.1.1")
+	t.Setenv("TG_TIME", "2024-01-02T03:04:05Z")
+	t.Setenv("TG_DATE", "2024-01-02")
+
+	if u := URL("TG_URL"); u.Host != "api.example.com" || u.Path != "/v1" {
+		t.Fatalf("URL = %v", u)
+	}
+	if u := URL("TG_BADURL", nil); u != nil {
+		t.Fatalf("bad URL = %v, want default", u)
+	}
+	if ip := IP("TG_IP"); !ip.Equal(net.IPv6loopback) {
+		t.Fatalf("IP = %v", ip)
+	}
+	if ip := IP("TG_BADIP", net.IPv4zero); !ip.Equal(net.IPv4zero) {
+		t.Fatalf("bad IP = %v, want default", ip)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := Time("TG_TIME", ""); !got.Equal(want) {
+		t.Fatalf("Time = %v", got)
+	}
+	if got := Time("TG_DATE", time.DateOnly); !got.Equal(want.Truncate(24 * time.Hour)) {
+		t.Fatalf("Time with layout = %v", got)
+	}
+	if got := Time("TG_DATE", "", want); !got.Equal(want) {
+		t.Fatalf("malformed Time = %v, want default", got)
+	}
+}
+
+func TestGetAs(t *testing.T) {
+	unsetenv(t, "GA_UNSET")
+	t.Setenv("GA_INT", "42")
+	t.Setenv("GA_DUR", "2s")
+	t.Setenv("GA_LIST", "1, 2,3")
+	t.Setenv("GA_BAD", "x")
+
+	if GetAs("GA_INT", 0) != 42 || GetAs("GA_INT", "") != "42" || GetAs("GA_INT", 0.0) != 42.0 {
+		t.Fatal("scalar dispatch")
+	}
+	if GetAs("GA_DUR", time.Duration(0)) != 2*time.Second {
+		t.Fatal("duration")
+	}
+	if got := GetAs[[]int]("GA_LIST", nil); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("slice = %v", got)
+	}
+	if GetAs("GA_BAD", true) != true || GetAs("GA_UNSET", uint8(9)) != 9 {
+		t.Fatal("defaults")
+	}
+}
+
+func TestCheckedGetters(t *testing.T) {
+	unsetenv(t, "CK_UNSET")
+	t.Setenv("CK_INT", "12")
+	t.Setenv("CK_BOOL", "yes")
+	t.Setenv("CK_DUR", "90s")
+
+	if _, err := StringE("CK_UNSET"); !errors.Is(err, ErrNotSet) {
+		t.Fatalf("StringE unset err = %v", err)
+	}
+	if _, err := IntE("CK_UNSET"); !errors.Is(err, ErrNotSet) {
+		t.Fatalf("IntE unset err = %v", err)
+	}
+	if i, err := IntE("CK_INT"); err != nil || i != 12 {
+		t.Fatalf("IntE = %d, %v", i, err)
+	}
+	if _, err := BoolE("CK_BOOL"); err == nil || err.Error() != `env CK_BOOL: invalid bool "yes"` {
+		t.Fatalf("BoolE err = %v", err)
+	}
+	if d, err := DurationE("CK_DUR"); err != nil || d != 90*time.Second {
+		t.Fatalf("DurationE = %v, %v", d, err)
+	}
+	if Duration("CK_BOOL", time.Second) != time.Second {
+		t.Fatal("Duration should fall back on malformed value")
+	}
+
+	defer func() {
+		msg, _ := recover().(string)
+		if msg != "invalid duration env CK_BOOL: yes" {
+			t.Fatalf("unexpected panic %q", msg)
+		}
+	}()
+	Duration("CK_BOOL")
+}
+
+func TestStrictParse(t *testing.T) {
+	unsetenv(t, "SP_A", "SP_B")
+	p := writeEnv(t, "# ok\nSP_A=1\n\nFOOBAR\nSP_B=2\n")
+	values, err := LoadFiles([]string{p}, nil)
+	if err != nil || values["SP_B"] != "2" {
+		t.Fatalf("lenient: %v, %v", values, err)
+	}
+
+	_, err = LoadFiles([]string{p}, &Options{Strict: true})
+	if err == nil || err.Error() != p+`:4: malformed line "FOOBAR"` {
+		t.Fatalf("strict err = %v", err)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	unsetenv(t, "PS_HOST", "PS_URL")
+	t.Setenv("PS_USER", "os-user")
+	got, err := ParseString("PS_HOST=db\nPS_URL=postgres://${PS_USER}@${PS_HOST}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"PS_HOST": "db", "PS_URL": "postgres://os-user@db"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("got %v", got)
+	}
+	if _, set := os.LookupEnv("PS_HOST"); set {
+		t.Fatal("Parse must not set the process environment")
+	}
+
+	var pe *ParseError
+	if _, err := Parse(strings.NewReader("A=\"\\q\"\nB=\"open\n")); !errors.As(err, &pe) || pe.Line != 2 {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	unsetenv(t, "FS_PORT", "FS_MODE")
+	fsys := fstest.MapFS{".env.defaults": {Data: []byte("FS_PORT=8080\nFS_MODE=prod\n")}}
+	if _, err := LoadFS(fsys, []string{".env.defaults", "missing.env"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	p := writeEnv(t, "FS_MODE=dev\n")
+	if _, err := LoadFiles([]string{p}, &Options{Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("FS_PORT") != "8080" || os.Getenv("FS_MODE") != "dev" {
+		t.Fatalf("PORT=%q MODE=%q", os.Getenv("FS_PORT"), os.Getenv("FS_MODE"))
+	}
+
+	fsys["bad.env"] = &fstest.MapFile{Data: []byte("X=\"open\n")}
+	if _, err := LoadFS(fsys, []string{"bad.env"}, nil); err == nil || !strings.HasPrefix(err.Error(), "bad.env:1:") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	unsetenv(t, "SN_NEW")
+	t.Setenv("SN_KEEP", "orig")
+	t.Setenv("SN_DROP", "x")
+
+	snap := Snapshot()
+	if snap["SN_KEEP"] != "orig" {
+		t.Fatalf("snapshot missing SN_KEEP: %q", snap["SN_KEEP"])
+	}
+	os.Setenv("SN_KEEP", "changed")
+	os.Setenv("SN_NEW", "added")
+	os.Unsetenv("SN_DROP")
+
+	if err := Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("SN_KEEP") != "orig" || os.Getenv("SN_DROP") != "x" {
+		t.Fatalf("KEEP=%q DROP=%q", os.Getenv("SN_KEEP"), os.Getenv("SN_DROP"))
+	}
+	if _, set := os.LookupEnv("SN_NEW"); set {
+		t.Fatal("SN_NEW should be unset")
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	unsetenv(t, "EV_PORT", "EV_DEBUG")
+	t.Setenv("EV_OS", "from-os")
+	t.Setenv("EV_NAME", "os-name")
+	p := writeEnv(t, "EV_PORT=8080\nEV_DEBUG=true\nEV_NAME=file-name\nEV_URL=http://${EV_NAME}:${EV_PORT}\n")
+
+	e, err := LoadEnvironment([]string{p}, &Options{Expand: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, set := os.LookupEnv("EV_PORT"); set {
+		t.Fatal("LoadEnvironment must not set the process environment")
+	}
+	if e.Int("EV_PORT") != 8080 || !e.Bool("EV_DEBUG") || e.String("EV_OS") != "from-os" {
+		t.Fatalf("values = %v", e.Values())
+	}
+	if e.String("EV_NAME") != "os-name" || e.String("EV_URL") != "http://os-name:8080" {
+		t.Fatalf("OS should win without Overwrite: %v", e.Values())
+	}
+	if e.Duration("EV_MISSING", time.Second) != time.Second {
+		t.Fatal("default")
+	}
+
+	e2 := NewEnvironment(map[string]string{"EV_OS": "overlay"})
+	if e2.String("EV_OS") != "overlay" {
+		t.Fatal("overlay should shadow the process environment")
+	}
+}
+
+func TestExample(t *testing.T) {
+	type db struct {
+		URL string `env:"URL,required" desc:"Postgres connection string"`
+	}
+	type config struct {
+		Port    int           `env:"PORT,default=8080" desc:"HTTP listen port"`
+		Timeout time.Duration `env:"TIMEOUT,default=5s"`
+		Greet   string        `env:"GREETING,default=hello world"`
+		DB      db            `env:"DB"`
+		skip    string        `env:"SKIP"`
+	}
+	got, err := Example("APP_", &config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HTTP listen port
+APP_PORT=8080
+
+APP_TIMEOUT=5s
+
+APP_GREETING='hello world'
+
+# Postgres connection string
+# (required)
+APP_DB_URL=
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	prod, staging := filepath.Join(dir, "prod.env"), filepath.Join(dir, "staging.env")
+	os.WriteFile(prod, []byte("HOST=prod\nDB_PASSWORD=p1\nOLD=x\nSAME=1\n"), 0o600)
+	os.WriteFile(staging, []byte("HOST=staging\nDB_PASSWORD=p2\nNEW_TOKEN=t\nSAME=1\n"), 0o600)
+
+	added, removed, changed, err := Diff(prod, staging, &DiffOptions{Mask: SecretKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(added, map[string]string{"NEW_TOKEN": "****"}) || !maps.Equal(removed, map[string]string{"OLD": "x"}) {
+		t.Fatalf("added %v removed %v", added, removed)
+	}
+	want := map[string]Change{"HOST": {"prod", "staging"}, "DB_PASSWORD": {"****", "****"}}
+	if !maps.Equal(changed, want) {
+		t.Fatalf("changed %v", changed)
+	}
+
+	if _, _, _, err := Diff(prod, filepath.Join(dir, "missing.env"), nil); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	// references compare as written, whatever the process env holds
+	t.Setenv("DIFF_REGION", "eu")
+	a, b := filepath.Join(dir, "a.env"), filepath.Join(dir, "b.env")
+	os.WriteFile(a, []byte("URL=https://${DIFF_REGION}.example.com\n"), 0o600)
+	os.WriteFile(b, []byte("URL=https://eu.example.com\n"), 0o600)
+	_, _, changed, err = Diff(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Change{"https://${DIFF_REGION}.example.com", "https://eu.example.com"}); changed["URL"] != want {
+		t.Fatalf("changed %v", changed)
+	}
+}
+
+func TestExport(t *testing.T) {
+	got := Export(map[string]string{"B": "2", "A": "x=y", "C": ""})
+	if !slices.Equal(got, []string{"A=x=y", "B=2", "C="}) {
+		t.Fatalf("Export = %q", got)
+	}
+
+	t.Setenv("EX_OS", "os")
+	t.Setenv("EX_OVER", "os")
+	environ := NewEnvironment(map[string]string{"EX_OVER": "overlay"}).Environ()
+	if !slices.Contains(environ, "EX_OS=os") || !slices.Contains(environ, "EX_OVER=overlay") || slices.Contains(environ, "EX_OVER=os") {
+		t.Fatal("Environ should merge the overlay over the process environment")
+	}
+}
+
+func TestLoadConvention(t *testing.T) {
+	if got := ConventionFiles("test"); !slices.Equal(got, []string{".env", ".env.test", ".env.test.local"}) {
+		t.Fatalf("test files = %q", got)
+	}
+	unsetenv(t, "CV_A", "CV_B", "CV_C", "CV_D")
+	t.Chdir(t.TempDir())
+	for name, content := range map[string]string{
+		".env":                  "CV_A=base\nCV_B=base\nCV_C=base\nCV_D=base\n",
+		".env.local":            "CV_B=local\nCV_C=local\nCV_D=local\n",
+		".env.production":       "CV_C=prod\nCV_D=prod\n",
+		".env.production.local": "CV_D=prod-local\n",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	values, err := LoadConvention("production", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"CV_A": "base", "CV_B": "local", "CV_C": "prod", "CV_D": "prod-local"}
+	if !maps.Equal(values, want) {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	got, err := ParseString(`E="tab\there\r\\ \"q\" caf\u00e9 \U0001F600 \uZZZZ"` + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tab\there\r\\ \"q\" café 😀 \\uZZZZ"; got["E"] != want {
+		t.Fatalf("E = %q, want %q", got["E"], want)
+	}
+
+	if _, err := parse(strings.NewReader(`E="\uZZZZ"`), &Options{StrictEscapes: true}); err == nil {
+		t.Fatal("strict should reject a malformed \\u escape")
+	}
+
+	es, err := parse(strings.NewReader(`E="raw\né"`), &Options{RawValues: true})
+	if err != nil || es[0].val != `raw\né` {
+		t.Fatalf("raw = %q, %v", es[0].val, err)
+	}
+}
+
+func TestUnused(t *testing.T) {
+	unsetenv(t, "UN_A", "UN_B", "UN_C")
+	ResetAccessed()
+	p := writeEnv(t, "UN_A=1\nUN_B=2\nUN_C=x\n")
+	loaded, err := LoadFiles([]string{p}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = Int("UN_A")
+	_ = NewEnvironment(nil).Bool("UN_C", false)
+	if got := Unused(loaded); len(got) != 1 || got[0] != "UN_B" {
+		t.Fatalf("Unused = %v", got)
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	unsetenv(t, "CI_PORT", "CI_HOST", "CI_URL", "ci_port", "ci_url")
+	t.Setenv("CI_HOST", "example.com")
+	t.Cleanup(func() { envFold.Store(nil) })
+	p := writeEnv(t, "ci_port=80\nCI_PORT=8080\nci_host=local\nci_url=http://${ci_host}:${Ci_Port}\n")
+	values, err := LoadFiles([]string{p}, &Options{Expand: true, CaseInsensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := values["CI_PORT"]; ok || values["ci_port"] != "8080" {
+		t.Fatalf("values = %v", values)
+	}
+	if got := String("ci_url"); got != "http://example.com:8080" {
+		t.Fatalf("ci_url = %q", got)
+	}
+	if got := Int("CI_PORT"); got != 8080 {
+		t.Fatalf("CI_PORT = %d", got)
+	}
+	if got := String("ci_host"); got != "example.com" {
+		t.Fatalf("ci_host = %q", got)
+	}
+
+	// a later load without the option turns the fallback off
+	if _, err := LoadFiles([]string{writeEnv(t, "")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := String("CI_PORT", "unset"); got != "unset" {
+		t.Fatalf("CI_PORT after plain load = %q", got)
+	}
+
+	// an Environment folds its own lookups without touching the getters
+	e, err := LoadEnvironment([]string{writeEnv(t, "CI_ENV_NAME=demo\n")}, &Options{CaseInsensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String("ci_env_name") != "demo" || e.String("ci_Host") != "example.com" {
+		t.Fatalf("Environment lookups: %q %q", e.String("ci_env_name", ""), e.String("ci_Host", ""))
+	}
+	if got := String("ci_host", "unset"); got != "unset" {
+		t.Fatalf("LoadEnvironment must not change the getters: %q", got)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	t.Setenv("BF_DB_HOST", "db.local")
+	t.Setenv("BF_PORT", "5432")
+	t.Setenv("BF_VERBOSE", "maybe")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	host := fs.String("db-host", "localhost", "")
+	port := fs.Int("port", 0, "")
+	name := fs.String("name", "app", "")
+	fs.Bool("verbose", false, "")
+
+	err := BindFlags(fs, "BF")
+	if err == nil || !strings.Contains(err.Error(), "BF_VERBOSE") {
+		t.Fatalf("err = %v, want BF_VERBOSE rejected", err)
+	}
+	if err := fs.Parse([]string{"-port", "6543"}); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "db.local" || *port != 6543 || *name != "app" {
+		t.Fatalf("host=%q port=%d name=%q", *host, *port, *name)
+	}
+	if d := fs.Lookup("db-host").DefValue; d != "db.local" {
+		t.Fatalf("DefValue = %q", d)
+	}
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	unsetenv(t, "HUP_A")
+	p := writeEnv(t, "HUP_A=1\n")
+	deltas := make(chan map[string]string, 1)
+	stop, err := ReloadOnSIGHUP([]string{p}, nil, func(d map[string]string) { deltas <- d })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if err := os.WriteFile(p, []byte("HUP_A=2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	proc, _ := os.FindProcess(os.Getpid())
+	if err := proc.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("cannot send SIGHUP:", err)
+	}
+	select {
+	case d := <-deltas:
+		if d["HUP_A"] != "2" || os.Getenv("HUP_A") != "2" {
+			t.Fatalf("delta = %v, HUP_A = %q", d, os.Getenv("HUP_A"))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reload")
+	}
+
+	stop()
+	stop() // must not panic
+}
+
+func TestSchemaValidate(t *testing.T) {
+	one, maxPort, eight := 1.0, 65535.0, 8.0
+	s := Schema{
+		"PORT":      {Kind: KindInt, Required: true, Min: &one, Max: &maxPort},
+		"LOG_LEVEL": {Enum: []string{"debug", "info", "warn", "error"}},
+		"TIMEOUT":   {Kind: KindDuration, Max: &eight},
+		"NAME":      {Pattern: `[a-z]+`, Min: &one},
+		"DB_URL":    {Required: true},
+	}
+	if err := s.Validate(map[string]string{"PORT": "8080", "LOG_LEVEL": "info", "TIMEOUT": "5s", "DB_URL": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Validate(map[string]string{"PORT": "70000", "LOG_LEVEL": "trace", "TIMEOUT": "soon", "NAME": "Bob"})
+	var se SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v", err)
+	}
+	var keys []string
+	for _, v := range se {
+		keys = append(keys, v.Key)
+	}
+	if want := []string{"DB_URL", "LOG_LEVEL", "NAME", "PORT", "TIMEOUT"}; !slices.Equal(keys, want) {
+		t.Fatalf("violations = %v", se)
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Setenv("DMP_HOST", "db")
+	t.Setenv("DMP_PASSWORD", "hunter2")
+	t.Setenv("DMP_TOKEN", "")
+	t.Setenv("OTHER_DMP", "x")
+
+	p := filepath.Join(t.TempDir(), "dump.env")
+	if err := Dump(p, "DMP_", true); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseString(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"DMP_HOST": "db", "DMP_PASSWORD": "****", "DMP_TOKEN": ""}
+	if !maps.Equal(got, want) {
+		t.Fatalf("Dump = %v", got)
+	}
+}
+
+func TestMap(t *testing.T) {
+	t.Setenv("RATE_LIMITS", " api:100, web : 50,,")
+	t.Setenv("BAD_LIMITS", "api:100,web")
+	t.Setenv("NAN_LIMITS", "api:many")
+	unsetenv(t, "NO_LIMITS")
+
+	if got := Map("RATE_LIMITS", ",", ":", nil); !maps.Equal(got, map[string]string{"api": "100", "web": "50"}) {
+		t.Fatalf("Map = %v", got)
+	}
+	if got := MapInt("RATE_LIMITS", ",", ":", nil); !maps.Equal(got, map[string]int{"api": 100, "web": 50}) {
+		t.Fatalf("MapInt = %v", got)
+	}
+	def := map[string]string{"d": "1"}
+	for _, k := range []string{"BAD_LIMITS", "NO_LIMITS"} {
+		if got := Map(k, ",", ":", def); !maps.Equal(got, def) {
+			t.Fatalf("Map(%s) = %v, want default", k, got)
+		}
+	}
+	if got := MapInt("NAN_LIMITS", ",", ":", nil); got != nil {
+		t.Fatalf("MapInt(NAN_LIMITS) = %v, want default", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	t.Setenv("JS_FEATURES", `{"name":"beta","limits":{"api":100}}`)
+	t.Setenv("JS_BAD", `{"name":`)
+	unsetenv(t, "JS_MISSING")
+
+	var got struct {
+		Name   string         `json:"name"`
+		Limits map[string]int `json:"limits"`
+	}
+	if err := JSON("JS_FEATURES", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "beta" || got.Limits["api"] != 100 {
+		t.Fatalf("JSON = %+v", got)
+	}
+	if err := JSON("JS_BAD", &got); err == nil || !strings.Contains(err.Error(), "JS_BAD") {
+		t.Fatalf("bad JSON err = %v", err)
+	}
+	if err := JSON("JS_MISSING", &got); !errors.Is(err, ErrNotSet) {
+		t.Fatalf("missing err = %v", err)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	t.Setenv("BIN_STD", "aGk/Pz8=")
+	t.Setenv("BIN_URL", "aGk_Pz8")
+	t.Setenv("BIN_HEX", " 6869ff ")
+	t.Setenv("BIN_BAD", "not base64!")
+
+	for _, k := range []string{"BIN_STD", "BIN_URL"} {
+		if got := Base64(k); string(got) != "hi???" {
+			t.Fatalf("Base64(%s) = %q", k, got)
+		}
+	}
+	if got := Hex("BIN_HEX"); string(got) != "hi\xff" {
+		t.Fatalf("Hex = %q", got)
+	}
+	if got := Base64("BIN_BAD", nil); got != nil {
+		t.Fatalf("Base64(BIN_BAD) = %q, want default", got)
+	}
+	if got := Hex("BIN_BAD", []byte("d")); string(got) != "d" {
+		t.Fatalf("Hex(BIN_BAD) = %q, want default", got)
+	}
+}
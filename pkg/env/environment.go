@@ -0,0 +1,167 @@
+package env
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment is a read-only overlay of key/values over the process
+// environment: lookups check its own values first, then os.LookupEnv, then
+// any providers. It never calls os.Setenv, so libraries can load config
+// without mutating global state. Getters behave like the package-level ones;
+// a provider failure counts as unset and is reported by Err.
+type Environment struct {
+	values    map[string]string
+	providers []Provider
+	fold      keyFold // set with Options.CaseInsensitive
+
+	mu  sync.Mutex
+	err error // first provider failure
+}
+
+// NewEnvironment returns an Environment holding a copy of values.
+func NewEnvironment(values map[string]string) *Environment {
+	return &Environment{values: maps.Clone(values)}
+}
+
+// LoadEnvironment reads .env files like LoadFiles but returns them as an
+// Environment instead of applying them. Options.Overwrite decides whether
+// file values or process variables win, in lookups and expansion alike, and
+// Options.Providers back keys set in neither.
+func LoadEnvironment(paths []string, opts *Options) (*Environment, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	values, _, _, err := resolveFiles(paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	for k := range values {
+		if opts.osWins(k) {
+			delete(values, k)
+		}
+	}
+	e := &Environment{values: values, providers: slices.Clone(opts.Providers)}
+	if opts.CaseInsensitive {
+		e.fold = newKeyFold()
+		for k := range values {
+			e.fold[strings.ToUpper(k)] = k // file spellings win
+		}
+	}
+	return e, nil
+}
+
+// Lookup returns the value for key and whether it is set. A provider
+// failure is treated as unset and kept for Err.
+func (e *Environment) Lookup(key string) (string, bool) {
+	v, ok, err := e.LookupE(key)
+	if err != nil {
+		e.mu.Lock()
+		if e.err == nil {
+			e.err = err
+		}
+		e.mu.Unlock()
+	}
+	return v, ok
+}
+
+// LookupE is Lookup returning a provider failure instead of keeping it.
+func (e *Environment) LookupE(key string) (string, bool, error) {
+	if _, ok := e.values[key]; !ok && e.fold != nil {
+		if _, ok := os.LookupEnv(key); !ok {
+			key = e.fold.spelling(key)
+		}
+	}
+	if v, ok := e.values[key]; ok {
+		accessed.Store(key, struct{}{})
+		return v, true, nil
+	}
+	if v, ok := lookupEnv(key); ok {
+		return v, true, nil
+	}
+	for _, p := range e.providers {
+		v, ok, err := p.Lookup(key)
+		if err != nil {
+			return "", false, fmt.Errorf("env: lookup %q: %w", key, err)
+		}
+		if ok {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Err returns the first provider failure seen by Lookup or the getters.
+func (e *Environment) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// Values returns a copy of the overlay's own values.
+func (e *Environment) Values() map[string]string { return maps.Clone(e.values) }
+
+// Environ returns the process environment with the overlay applied, as
+// sorted KEY=VALUE strings for exec.Cmd.Env.
+func (e *Environment) Environ() []string {
+	all := Snapshot()
+	maps.Copy(all, e.values)
+	return Export(all)
+}
+
+// lookupAs parses the value for key, falling back to def or panicking like
+// the package getters.
+func lookupAs[T any](e *Environment, key string, parse func(string) (T, error), def []T) T {
+	if v, ok := e.Lookup(key); ok {
+		if x, err := parse(strings.TrimSpace(v)); err == nil {
+			return x
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+func (e *Environment) String(key string, def ...string) string {
+	if v, ok := e.Lookup(key); ok && strings.TrimSpace(v) != "" {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+func (e *Environment) Int(key string, def ...int) int {
+	return lookupAs(e, key, func(s string) (int, error) {
+		i, err := parseInt(s, strconv.IntSize)
+		return int(i), err
+	}, def)
+}
+
+func (e *Environment) Int64(key string, def ...int64) int64 {
+	return lookupAs(e, key, func(s string) (int64, error) { return parseInt(s, 64) }, def)
+}
+
+func (e *Environment) Float64(key string, def ...float64) float64 {
+	return lookupAs(e, key, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }, def)
+}
+
+func (e *Environment) Bool(key string, def ...bool) bool {
+	return lookupAs(e, key, strconv.ParseBool, def)
+}
+
+func (e *Environment) Duration(key string, def ...time.Duration) time.Duration {
+	return lookupAs(e, key, time.ParseDuration, def)
+}
+
+func (e *Environment) Bytes(key string, def ...int64) int64 {
+	return lookupAs(e, key, parseBytes, def)
+}
@@ -0,0 +1,68 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Example renders a .env.example for the config struct v (or a pointer to
+// one) using the same tags as Unmarshal. Each key gets its `desc:"..."` tag
+// as a comment, is marked if required, and is set to its default (empty
+// when there is none).
+func Example(prefix string, v any) (string, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", errors.New("env: Example needs a struct")
+	}
+	var b strings.Builder
+	exampleStruct(&b, t, prefix)
+	return b.String(), nil
+}
+
+// WriteExample writes Example's output to path.
+func WriteExample(path, prefix string, v any) error {
+	s, err := Example(prefix, v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(s), 0o644)
+}
+
+func exampleStruct(b *strings.Builder, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("env")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		if p, ok := nestedPrefix(sf, prefix); ok {
+			exampleStruct(b, sf.Type, p)
+			continue
+		}
+		if !tagged {
+			continue
+		}
+		ft := parseTag(tag)
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		if d := sf.Tag.Get("desc"); d != "" {
+			for _, l := range strings.Split(d, "\n") {
+				b.WriteString(strings.TrimSpace("# " + l))
+				b.WriteByte('\n')
+			}
+		}
+		if ft.required {
+			b.WriteString("# (required)\n")
+		}
+		b.WriteString(prefix + ft.key)
+		b.WriteByte('=')
+		b.WriteString(quoteValue(ft.def))
+		b.WriteByte('\n')
+	}
+}
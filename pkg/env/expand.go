@@ -0,0 +1,253 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type lookupFunc func(key string) (string, bool, error)
+
+type expander struct {
+	lookup lookupFunc
+	// windows also expands %VAR% references.
+	windows bool
+}
+
+var errUnterminated = errors.New("unterminated ${ reference")
+
+// expand replaces $VAR and ${VAR} references (and %VAR% when windows is set;
+// such names must start with a letter or '_' and stay literal if undefined).
+// ${VAR:-default} uses default, itself expanded, when VAR is unset or empty,
+// ${VAR:?message} fails with message in that case, and
+// ${VAR|upper|default:x} pipes the value through filters.
+// Unknown references expand to the empty string. Braced names may contain
+// dots and other punctuation (${my.service.port}); bare $VAR names are
+// limited to letters, digits and '_'.
+func (x expander) expand(s string) (string, error) {
+	if !strings.ContainsAny(s, "$%") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		var name string
+		switch {
+		case c == escapedDollar[0] && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := matchBrace(s, i+1)
+			if end < 0 {
+				return "", errUnterminated
+			}
+			v, err := x.braced(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i = end
+			continue
+		case c == '$':
+			j := scanName(s, i+1)
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			name = s[i+1 : j]
+			i = j - 1
+		case c == '%' && x.windows:
+			if i+1 < len(s) && s[i+1] == '%' {
+				b.WriteByte('%')
+				i++
+				continue
+			}
+			j := scanName(s, i+1)
+			if j == i+1 || j >= len(s) || s[j] != '%' || !isNameStart(s[i+1]) {
+				b.WriteByte(c)
+				continue
+			}
+			v, ok, err := x.lookup(s[i+1 : j])
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				v = s[i : j+1] // like cmd.exe, undefined %NAME% stays literal
+			}
+			b.WriteString(v)
+			i = j
+			continue
+		default:
+			b.WriteByte(c)
+			continue
+		}
+		v, _, err := x.lookup(name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// cutOperator splits "NAME:-default" or "NAME:?message" at whichever
+// operator comes first. op is "" when there is none.
+func cutOperator(s string) (name, op, arg string) {
+	i := strings.Index(s, ":-")
+	if j := strings.Index(s, ":?"); j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+	if i < 0 {
+		return s, "", ""
+	}
+	return s[:i], s[i : i+2], s[i+2:]
+}
+
+// braced resolves the body of a ${...} reference, applying any |filters.
+func (x expander) braced(body string) (string, error) {
+	parts := splitTop(body, '|')
+	name, op, arg := cutOperator(parts[0])
+	v, _, err := x.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if v == "" && op != "" {
+		if arg, err = x.expand(arg); err != nil {
+			return "", err
+		}
+		if op == ":?" {
+			if arg == "" {
+				arg = "parameter null or not set"
+			}
+			return "", fmt.Errorf("%s: %s", name, arg)
+		}
+		v = arg
+	}
+	for _, f := range parts[1:] {
+		fname, arg, _ := strings.Cut(strings.TrimSpace(f), ":")
+		fn, ok := filters[fname]
+		if !ok {
+			return "", fmt.Errorf("unknown filter %q in ${%s}", fname, body)
+		}
+		v = fn(v, arg)
+	}
+	return v, nil
+}
+
+// filters are the transforms available as ${VAR|name[:arg]}. They apply left
+// to right; an unknown filter name is an error.
+var filters = map[string]func(v, arg string) string{
+	"upper":    func(v, _ string) string { return strings.ToUpper(v) },
+	"lower":    func(v, _ string) string { return strings.ToLower(v) },
+	"trim":     func(v, _ string) string { return strings.TrimSpace(v) },
+	"basename": func(v, _ string) string { return path.Base(filepath.ToSlash(v)) },
+	"default": func(v, arg string) string {
+		if v == "" {
+			return arg
+		}
+		return v
+	},
+}
+
+// splitTop splits s on sep, ignoring separators inside nested ${...}.
+func splitTop(s string, sep byte) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, s[start:])
+}
+
+// matchBrace returns the index of the '}' closing the '{' at open, honoring
+// nested ${...} references, or -1 if it is unbalanced.
+func matchBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// scanName returns the end index of the variable name starting at i.
+func scanName(s string, i int) int {
+	for i < len(s) && isNameChar(s[i]) {
+		i++
+	}
+	return i
+}
+
+// isNameStart reports whether c may begin a %NAME% reference, so that
+// percent-encoded text such as "%40" is left alone.
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Resolve returns a copy of m with every reference expanded, without touching
+// the process environment. References resolve against m first (in any order),
+// then extra if non-nil; anything else expands to "". Cycles are an error.
+func Resolve(m map[string]string, extra func(string) (string, bool)) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	visiting := map[string]bool{}
+
+	var x expander
+	var resolve func(key string) (string, error)
+	resolve = func(key string) (string, error) {
+		if v, ok := out[key]; ok {
+			return v, nil
+		}
+		if visiting[key] {
+			return "", fmt.Errorf("reference cycle at %q", key)
+		}
+		visiting[key] = true
+		v, err := x.expand(m[key])
+		if err != nil {
+			return "", err
+		}
+		delete(visiting, key)
+		out[key] = v
+		return v, nil
+	}
+	x.lookup = func(key string) (string, bool, error) {
+		if _, ok := m[key]; ok {
+			v, err := resolve(key)
+			return v, err == nil, err
+		}
+		if extra != nil {
+			v, ok := extra(key)
+			return v, ok, nil
+		}
+		return "", false, nil
+	}
+
+	for k := range m {
+		if _, err := resolve(k); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,36 @@
+package env
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// BindFlags fills the default of every flag defined on fs from the
+// environment variable PREFIX_NAME, where NAME is the flag name upper-cased
+// with '-' and '.' turned into '_' (flag "db-host" with prefix "APP" reads
+// APP_DB_HOST). Call it after defining flags and before fs.Parse, so
+// command-line values still win. Unset or blank variables leave the default
+// alone; a value the flag rejects is an error.
+func BindFlags(fs *flag.FlagSet, prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		key := prefix + flagKey.Replace(strings.ToUpper(f.Name))
+		v, ok := lookupEnv(key)
+		if !ok || strings.TrimSpace(v) == "" {
+			return
+		}
+		if err := f.Value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+		f.DefValue = f.Value.String()
+	})
+	return errors.Join(errs...)
+}
+
+var flagKey = strings.NewReplacer("-", "_", ".", "_")
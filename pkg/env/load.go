@@ -0,0 +1,405 @@
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Options controls how LoadFiles reads and applies .env files.
+type Options struct {
+	// Overwrite replaces variables already set in the process environment.
+	Overwrite bool
+	// Providers are value layers below the loaded files and the process
+	// environment, consulted in order. They resolve ${VAR} references, and
+	// an Environment from LoadEnvironment falls back to them for keys that
+	// are otherwise unset. The package getters never consult them.
+	Providers []Provider
+	// Expand replaces $VAR and ${VAR} references, including the
+	// ${VAR:-default}, ${VAR:?message} and ${VAR|filter} forms, in unquoted
+	// and double-quoted values. Without it values are taken as written.
+	Expand bool
+	// InlineComments drops a trailing " #comment" from unquoted values.
+	InlineComments bool
+	// WindowsExpand also expands %VAR% references when Expand is set.
+	// Undefined ones, and %-sequences not starting with a letter or '_'
+	// (percent-encoding such as %40), are kept literally.
+	WindowsExpand bool
+	// NormalizeKey rewrites every parsed key before it is merged and applied,
+	// e.g. strings.ToUpper. Keys normalized to "" are dropped.
+	NormalizeKey func(string) string
+	// Strict fails on lines that are not assignments, comments or blank
+	// (e.g. "FOOBAR") instead of skipping them.
+	Strict bool
+	// StrictEscapes rejects unknown backslash escapes (e.g. \q) in
+	// double-quoted values instead of keeping them literally.
+	StrictEscapes bool
+	// RawValues turns off escape processing in double-quoted values, which
+	// are then taken verbatim like single-quoted ones (but still expanded with Expand).
+	RawValues bool
+	// DefaultFiles overrides the package DefaultFiles for LoadDefault and
+	// for LoadFiles called without paths.
+	DefaultFiles []string
+	// AccumulateKeys lists keys whose repeated assignments are collected
+	// (joined with AccumulateSep) instead of the last one winning.
+	AccumulateKeys []string
+	// AccumulateSep joins accumulated values. Defaults to ",".
+	AccumulateSep string
+	// Sections treats INI-style [name] lines as headers: keys after one are
+	// prefixed with NAME_ until the next header ("[]" ends the section).
+	Sections bool
+	// InferTypes makes LoadTyped convert unquoted values to int, float64,
+	// bool or decoded JSON where they parse as such.
+	InferTypes bool
+	// CaseInsensitive matches keys regardless of case: a key differing only
+	// in case from one already loaded or set in the process environment
+	// takes over that spelling. Once such a load has been applied the
+	// getters fall back to a case-insensitive match against the environment
+	// as it was then, so String("port") finds PORT; the next load applied
+	// without it turns this off again. An Environment from LoadEnvironment
+	// does the same for its own lookups only.
+	CaseInsensitive bool
+	// OnSet is called after each variable is applied to the process
+	// environment; overwritten reports whether it replaced an existing value.
+	OnSet func(key, value string, overwritten bool)
+
+	owned map[string]bool // keys Watch set itself, which it may overwrite
+}
+
+// DefaultFiles are the files LoadDefault reads, in order, when
+// Options.DefaultFiles is empty. An empty list falls back to ".env".
+var DefaultFiles = []string{".env"}
+
+// Load reads a single .env file into the process environment. A missing file
+// is not an error and existing variables are left untouched. There is no
+// expansion or comment stripping (see LoadFiles): a value in matching
+// single quotes is taken verbatim, one in matching double quotes has its
+// backslash escapes resolved, and otherwise only stray surrounding quotes
+// are trimmed. This reads back whatever Marshal and Write produce.
+func Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := splitKV(trimDeclare(line))
+		if !ok {
+			continue
+		}
+		val = literalValue(val)
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, val); err != nil {
+			return fmt.Errorf("setenv %q: %w", key, err)
+		}
+	}
+	return sc.Err()
+}
+
+// literalValue unquotes a value for Load.
+func literalValue(val string) string {
+	if n := len(val); n >= 2 && val[0] == val[n-1] && (val[0] == '\'' || val[0] == '"') {
+		if val[0] == '\'' {
+			return val[1 : n-1]
+		}
+		v, _ := unescape(val[1:n-1], false)
+		return strings.ReplaceAll(v, escapedDollar, "$")
+	}
+	return strings.Trim(val, `"'`)
+}
+
+// LoadDefault loads the default files; see DefaultFiles.
+func LoadDefault(opts *Options) (map[string]string, error) {
+	return LoadFiles(nil, opts)
+}
+
+// LoadResult reports what LoadFilesResult did. Applied and Skipped list keys
+// in file order; Skipped holds keys left alone because they were already set
+// and Options.Overwrite was false.
+type LoadResult struct {
+	Values  map[string]string
+	Applied []string
+	Skipped []string
+
+	quoted map[string]bool // keys whose last assignment was quoted
+}
+
+// LoadFiles reads the given .env files in order (later files win), expands
+// references if Options.Expand is set and applies the result to the process
+// environment. Missing files are skipped and no paths means the default
+// files. It returns the merged values.
+func LoadFiles(paths []string, opts *Options) (map[string]string, error) {
+	res, err := LoadFilesResult(paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+// LoadFilesResult is LoadFiles that also reports which keys were applied to
+// the process environment and which were skipped.
+func LoadFilesResult(paths []string, opts *Options) (*LoadResult, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	values, order, quoted, err := resolveFiles(paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	return apply(values, order, quoted, opts)
+}
+
+// apply sets the resolved values in the process environment, in order.
+func apply(values map[string]string, order []string, quoted map[string]bool, opts *Options) (*LoadResult, error) {
+	res := &LoadResult{Values: values, quoted: quoted}
+	for _, k := range order {
+		_, set := os.LookupEnv(k)
+		if opts.osWins(k) {
+			res.Skipped = append(res.Skipped, k)
+			continue
+		}
+		if err := os.Setenv(k, values[k]); err != nil {
+			return nil, fmt.Errorf("setenv %q: %w", k, err)
+		}
+		res.Applied = append(res.Applied, k)
+		if opts.OnSet != nil {
+			opts.OnSet(k, values[k], set)
+		}
+	}
+	if opts.CaseInsensitive {
+		f := newKeyFold()
+		envFold.Store(&f)
+	} else {
+		envFold.Store(nil)
+	}
+	return res, nil
+}
+
+// resolveFiles parses and expands paths without touching the process
+// environment. order lists keys by first appearance.
+func resolveFiles(paths []string, opts *Options) (values map[string]string, order []string, quoted map[string]bool, err error) {
+	paths = filenamesOrDefault(paths, opts)
+	var entries []entry
+	for _, p := range paths {
+		es, err := parseFile(p, opts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		entries = append(entries, es...)
+	}
+	return resolveEntries(entries, opts)
+}
+
+// resolveEntries normalizes, expands and merges parsed entries.
+func resolveEntries(entries []entry, opts *Options) (values map[string]string, order []string, quoted map[string]bool, err error) {
+	values = make(map[string]string, len(entries))
+	quoted = map[string]bool{}
+	var fold keyFold
+	if opts.CaseInsensitive {
+		fold = newKeyFold()
+	}
+	x := expander{
+		lookup:  opts.lookup(values, fold),
+		windows: opts.WindowsExpand,
+	}
+	for _, e := range entries {
+		if opts.NormalizeKey != nil {
+			if e.key = opts.NormalizeKey(e.key); e.key == "" {
+				continue
+			}
+		}
+		if opts.CaseInsensitive {
+			e.key = fold.resolve(e.key, values)
+		}
+		v := e.val
+		if opts.Expand && e.quote != '\'' {
+			var err error
+			if v, err = x.expand(v); err != nil {
+				return nil, nil, nil, fmt.Errorf("expand %q: %w", e.key, err)
+			}
+		} else if e.quote == '"' {
+			v = strings.ReplaceAll(v, escapedDollar, "$")
+		}
+		prev, seen := values[e.key]
+		if !seen {
+			order = append(order, e.key)
+		} else if slices.Contains(opts.AccumulateKeys, e.key) {
+			v = prev + opts.accumulateSep() + v
+		}
+		values[e.key] = v
+		quoted[e.key] = e.quote != 0
+	}
+	return values, order, quoted, nil
+}
+
+// ConventionFiles lists the files LoadConvention reads, lowest precedence
+// first: .env, .env.local, .env.{appEnv}, .env.{appEnv}.local. As with
+// dotenv and Vite, .env.local is left out when appEnv is "test" so test
+// runs are reproducible.
+func ConventionFiles(appEnv string) []string {
+	files := []string{".env"}
+	if appEnv != "test" {
+		files = append(files, ".env.local")
+	}
+	if appEnv != "" {
+		files = append(files, ".env."+appEnv, ".env."+appEnv+".local")
+	}
+	return files
+}
+
+// LoadConvention loads ConventionFiles(appEnv) from the working directory,
+// later files overriding earlier ones.
+func LoadConvention(appEnv string, opts *Options) (map[string]string, error) {
+	return LoadFiles(ConventionFiles(appEnv), opts)
+}
+
+// LoadFS is LoadFiles reading names from fsys, e.g. defaults embedded with
+// //go:embed. Missing names are skipped. Call LoadFiles afterwards with
+// Overwrite to layer real files on top.
+func LoadFS(fsys fs.FS, names []string, opts *Options) (map[string]string, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var entries []entry
+	for _, name := range filenamesOrDefault(names, opts) {
+		es, err := parseOpened(name, opts, func() (io.ReadCloser, error) { return fsys.Open(name) })
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+	values, order, quoted, err := resolveEntries(entries, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := apply(values, order, quoted, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Values, nil
+}
+
+// MustLoad is LoadFiles for program init: it panics if loading fails or if
+// any required key is missing or blank afterwards, naming every missing key.
+func MustLoad(paths []string, opts *Options, required ...string) map[string]string {
+	values, err := LoadFiles(paths, opts)
+	if err != nil {
+		panic("env: " + err.Error())
+	}
+	if miss := missing(required); len(miss) > 0 {
+		panic("missing env: " + strings.Join(miss, ", "))
+	}
+	return values
+}
+
+// Require returns an error naming every key that is unset or blank, or nil
+// if all are present.
+func Require(keys ...string) error {
+	if miss := missing(keys); len(miss) > 0 {
+		return errors.New("missing env: " + strings.Join(miss, ", "))
+	}
+	return nil
+}
+
+// missing returns the keys that are unset or blank in the process environment.
+func missing(keys []string) []string {
+	var out []string
+	for _, k := range keys {
+		if v, ok := lookupEnv(k); !ok || strings.TrimSpace(v) == "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// osWins reports whether an existing process variable takes precedence over
+// a loaded value for key.
+func (o *Options) osWins(key string) bool {
+	if o.Overwrite || o.owned[key] {
+		return false
+	}
+	_, set := os.LookupEnv(key)
+	return set
+}
+
+func (o *Options) accumulateSep() string {
+	if o.AccumulateSep == "" {
+		return ","
+	}
+	return o.AccumulateSep
+}
+
+func filenamesOrDefault(paths []string, opts *Options) []string {
+	switch {
+	case len(paths) > 0:
+		return paths
+	case len(opts.DefaultFiles) > 0:
+		return opts.DefaultFiles
+	case len(DefaultFiles) > 0:
+		return DefaultFiles
+	default:
+		return []string{".env"}
+	}
+}
+
+func parseFile(path string, opts *Options) ([]entry, error) {
+	return parseOpened(path, opts, func() (io.ReadCloser, error) { return os.Open(path) })
+}
+
+// parseOpened parses the file open returns, naming it path in errors. A
+// missing file yields no entries.
+func parseOpened(path string, opts *Options, open func() (io.ReadCloser, error)) ([]entry, error) {
+	f, err := open()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	es, err := parse(f, opts)
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.File = path
+	}
+	return es, err
+}
+
+// lookup resolves a reference against the values loaded so far, then the
+// process environment, then opts.Providers in order.
+func (o *Options) lookup(values map[string]string, fold keyFold) lookupFunc {
+	chain := append([]Provider{OSProvider{}}, o.Providers...)
+	return func(key string) (string, bool, error) {
+		if _, ok := values[key]; !ok && fold != nil {
+			key = fold.spelling(key)
+		}
+		if v, ok := values[key]; ok && !o.osWins(key) {
+			return v, true, nil
+		}
+		for _, p := range chain {
+			v, ok, err := p.Lookup(key)
+			if err != nil {
+				return "", false, fmt.Errorf("lookup %q: %w", key, err)
+			}
+			if ok {
+				return v, true, nil
+			}
+		}
+		return "", false, nil
+	}
+}
@@ -0,0 +1,118 @@
+package env
+
+import (
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Marshal renders values in .env format with sorted keys, quoting values
+// so that Load, LoadFiles and Parse read them back unchanged.
+func Marshal(values map[string]string) string {
+	return marshal(values, false)
+}
+
+// WriteOptions controls Write.
+type WriteOptions struct {
+	Perm   fs.FileMode // default 0600, since .env files often hold secrets
+	Export bool        // prefix each line with "export " so the file can be sourced
+	Header string      // written first as "# " comment lines
+}
+
+// Write writes values to path in .env format (see Marshal). opts may be nil.
+func Write(path string, values map[string]string, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0o600
+	}
+	var b strings.Builder
+	if opts.Header != "" {
+		for _, l := range strings.Split(strings.TrimRight(opts.Header, "\n"), "\n") {
+			b.WriteString(strings.TrimSpace("# " + l))
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString(marshal(values, opts.Export))
+	return os.WriteFile(path, []byte(b.String()), perm)
+}
+
+func marshal(values map[string]string, export bool) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		if export {
+			b.WriteString("export ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(quoteValue(values[k]))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Export returns values as sorted KEY=VALUE strings, ready for exec.Cmd.Env.
+func Export(values map[string]string) []string {
+	out := make([]string, 0, len(values))
+	for k, v := range values {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ExportToFile writes the process environment, limited to keys starting with
+// prefix (all keys when empty), to path in .env format.
+func ExportToFile(path string, prefix string) error {
+	return Write(path, environ(prefix), nil)
+}
+
+// Dump is ExportToFile for debugging and support bundles: with maskSecrets,
+// non-empty values of keys that look secret (see SecretKey) are written as
+// "****".
+func Dump(path, prefix string, maskSecrets bool) error {
+	values := environ(prefix)
+	if maskSecrets {
+		for k, v := range values {
+			if v != "" && SecretKey(k) {
+				values[k] = "****"
+			}
+		}
+	}
+	return Write(path, values, nil)
+}
+
+// environ returns the process variables whose keys start with prefix.
+func environ(prefix string) map[string]string {
+	values := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		values[k] = v
+	}
+	return values
+}
+
+var dquoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+
+func quoteValue(v string) string {
+	if v == "" || !strings.ContainsAny(v, " \t\r\n\"'#$\\%=") {
+		return v
+	}
+	// single quotes are literal: no escapes, no expansion
+	if !strings.ContainsAny(v, "'\r\n") {
+		return "'" + v + "'"
+	}
+	return `"` + dquoteEscaper.Replace(v) + `"`
+}
@@ -0,0 +1,261 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError reports a problem at a line of a .env file.
+type ParseError struct {
+	File string // empty when parsing a reader
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Parse reads .env content from r and returns the expanded values without
+// touching the process environment. References resolve against earlier
+// values in the content first, then the process environment. Unlike
+// LoadFiles, expansion and inline comments are always on.
+func Parse(r io.Reader) (map[string]string, error) {
+	// content wins over the OS in lookups
+	opts := &Options{Overwrite: true, Expand: true, InlineComments: true}
+	es, err := parse(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	values, _, _, err := resolveEntries(es, opts)
+	return values, err
+}
+
+// ParseString is Parse for content already in memory.
+func ParseString(s string) (map[string]string, error) {
+	return Parse(strings.NewReader(s))
+}
+
+type entry struct {
+	key   string
+	val   string
+	line  int
+	quote byte // 0, '"' or '\''
+}
+
+func parse(r io.Reader, opts *Options) ([]entry, error) {
+	var out []entry
+	sc := bufio.NewScanner(r)
+	n := 0
+	prefix := ""
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if opts.Sections && strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			prefix = sectionPrefix(line[1 : len(line)-1])
+			continue
+		}
+		if key, raw, ok := splitKV(trimDeclare(line)); ok && strings.HasPrefix(raw, `"`) && !dquoteClosed(raw) {
+			// a double-quoted value continues over the following lines
+			start := n
+			for !dquoteClosed(raw) {
+				if !sc.Scan() {
+					if err := sc.Err(); err != nil {
+						return nil, err
+					}
+					return nil, &ParseError{Line: start, Msg: "unterminated double-quoted value"}
+				}
+				n++
+				raw += "\n" + strings.TrimRight(sc.Text(), "\r")
+			}
+			val, quote, err := unquote(strings.TrimSpace(raw), opts)
+			if err != nil {
+				return nil, &ParseError{Line: start, Msg: err.Error()}
+			}
+			out = append(out, entry{key: prefix + key, val: val, line: start, quote: quote})
+			continue
+		}
+		for _, stmt := range splitStatements(line) {
+			key, raw, ok := splitKV(trimDeclare(stmt))
+			if !ok || strings.ContainsAny(key, " \t") {
+				if opts.Strict {
+					return nil, &ParseError{Line: n, Msg: fmt.Sprintf("malformed line %q", stmt)}
+				}
+				continue
+			}
+			val, quote, err := unquote(raw, opts)
+			if err != nil {
+				return nil, &ParseError{Line: n, Msg: err.Error()}
+			}
+			out = append(out, entry{key: prefix + key, val: val, line: n, quote: quote})
+		}
+	}
+	return out, sc.Err()
+}
+
+// sectionPrefix turns a [section] name into a key prefix: "database" gives
+// "DATABASE_", other non-name characters become "_", and "[]" clears it.
+func sectionPrefix(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if !isNameChar(c) {
+			b[i] = '_'
+		}
+	}
+	return string(b) + "_"
+}
+
+// dquoteClosed reports whether raw, which starts with '"', contains the
+// matching unescaped closing quote.
+func dquoteClosed(raw string) bool {
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return true
+		}
+	}
+	return false
+}
+
+// declPrefixes are shell keywords that may precede an assignment.
+var declPrefixes = []string{"export ", "declare -x ", "typeset -x ", "typeset "}
+
+func trimDeclare(s string) string {
+	for _, p := range declPrefixes {
+		if rest, ok := strings.CutPrefix(s, p); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return s
+}
+
+// splitStatements splits "A=1; B=2" into separate assignments. A semicolon
+// only separates when it is outside quotes and followed by whitespace and
+// another NAME=, so values such as "a=1;b=2" are left intact.
+func splitStatements(line string) []string {
+	var out []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';' && i+1 < len(line) && (line[i+1] == ' ' || line[i+1] == '\t'):
+			rest := trimDeclare(strings.TrimSpace(line[i+1:]))
+			if j := scanName(rest, 0); j > 0 && j < len(rest) && rest[j] == '=' {
+				out = append(out, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(line[start:]))
+}
+
+func splitKV(line string) (key, val string, ok bool) {
+	i := strings.IndexByte(line, '=')
+	if i <= 0 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(line[:i])
+	if key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(line[i+1:]), true
+}
+
+// unquote strips matching quotes. Double-quoted values process escapes
+// unless opts.RawValues is set; single-quoted values are literal. Unquoted
+// values drop a trailing " #comment" with opts.InlineComments.
+func unquote(raw string, opts *Options) (string, byte, error) {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') {
+		q := raw[0]
+		if j := strings.LastIndexByte(raw, q); j > 0 {
+			v := raw[1:j]
+			if q == '"' && !opts.RawValues {
+				var err error
+				if v, err = unescape(v, opts.StrictEscapes); err != nil {
+					return "", 0, err
+				}
+			}
+			return v, q, nil
+		}
+	}
+	if i := strings.Index(raw, " #"); i >= 0 && opts.InlineComments {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, 0, nil
+}
+
+var escapes = map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '"': '"', '$': '$'}
+
+// escapedDollar stands in for an escaped \$ between unescape and expansion,
+// which turns it back into a literal '$' instead of a reference.
+const escapedDollar = "\x00$"
+
+// hexDigits is the length of the code point after \u and \U.
+var hexDigits = map[byte]int{'u': 4, 'U': 8}
+
+// unescape resolves backslash escapes: \n \t \r \\ \" \$ (as escapedDollar)
+// plus \uXXXX and \UXXXXXXXX code points. Unknown or malformed escapes are
+// kept literally, or rejected when strict is set.
+func unescape(s string, strict bool) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if c, ok := escapes[s[i+1]]; ok {
+			if c == '$' {
+				b.WriteString(escapedDollar)
+			} else {
+				b.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		if n := hexDigits[s[i+1]]; n > 0 && i+2+n <= len(s) {
+			if r, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32); err == nil && utf8.ValidRune(rune(r)) {
+				b.WriteRune(rune(r))
+				i += 1 + n
+				continue
+			}
+			if strict {
+				return "", fmt.Errorf("invalid escape %q", s[i:i+2+n])
+			}
+			b.WriteByte(s[i])
+			continue
+		}
+		if strict {
+			return "", fmt.Errorf("invalid escape %q", s[i:i+2])
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,25 @@
+package env
+
+import "os"
+
+// Provider supplies values from an external backend (Vault, SSM, ...).
+// Lookup reports whether the key exists; err is for backend failures only.
+type Provider interface {
+	Lookup(key string) (string, bool, error)
+}
+
+// MapProvider serves values from a fixed map.
+type MapProvider map[string]string
+
+func (m MapProvider) Lookup(key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+// OSProvider serves values from the process environment.
+type OSProvider struct{}
+
+func (OSProvider) Lookup(key string) (string, bool, error) {
+	v, ok := os.LookupEnv(key)
+	return v, ok, nil
+}
@@ -0,0 +1,91 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RemoteOptions configures LoadRemote.
+type RemoteOptions struct {
+	Options
+	// Header is sent with every request, e.g. Authorization.
+	Header http.Header
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Timeout bounds each request. Defaults to 10s; negative means none.
+	Timeout time.Duration
+}
+
+// maxRemoteSize caps a fetched .env document.
+const maxRemoteSize = 1 << 20
+
+// LoadRemote is LoadFilesResult for .env content served over HTTP(S): each
+// URL is fetched in order (later ones win) and must answer 200 OK.
+func LoadRemote(ctx context.Context, urls []string, opts *RemoteOptions) (*LoadResult, error) {
+	if opts == nil {
+		opts = &RemoteOptions{}
+	}
+	var entries []entry
+	for _, u := range urls {
+		es, err := fetchEnv(ctx, u, opts)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+	values, order, quoted, err := resolveEntries(entries, &opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	return apply(values, order, quoted, &opts.Options)
+}
+
+func fetchEnv(ctx context.Context, url string, opts *RemoteOptions) ([]entry, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range opts.Header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if len(body) > maxRemoteSize {
+		return nil, fmt.Errorf("fetch %s: response exceeds %d bytes", url, maxRemoteSize)
+	}
+	es, err := parse(bytes.NewReader(body), &opts.Options)
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.File = url
+	}
+	return es, err
+}
@@ -0,0 +1,139 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind is the type a Schema rule expects a value to parse as.
+type Kind string
+
+const (
+	KindString   Kind = "" // any value
+	KindInt      Kind = "int"
+	KindFloat    Kind = "float"
+	KindBool     Kind = "bool"
+	KindDuration Kind = "duration"
+)
+
+// Rule constrains one key of a Schema. Min and Max bound the number for
+// KindInt and KindFloat, the seconds for KindDuration and the length in
+// characters for strings; nil means unbounded. Pattern must match the whole
+// value and Enum, when non-empty, lists the allowed values.
+type Rule struct {
+	Kind     Kind
+	Required bool
+	Pattern  string
+	Min, Max *float64
+	Enum     []string
+}
+
+// Schema maps keys to the rules their values must satisfy, e.g.
+//
+//	env.Schema{
+//		"PORT":      {Kind: env.KindInt, Required: true, Min: &one, Max: &maxPort},
+//		"LOG_LEVEL": {Enum: []string{"debug", "info", "warn", "error"}},
+//	}
+type Schema map[string]Rule
+
+// Violation is a key that failed its rule.
+type Violation struct {
+	Key     string
+	Message string
+}
+
+func (v Violation) String() string { return v.Key + ": " + v.Message }
+
+// SchemaError lists every violation found by Schema.Validate, by key.
+type SchemaError []Violation
+
+func (e SchemaError) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.String()
+	}
+	return "invalid env: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks values against every rule and returns a SchemaError
+// listing all violations, or nil. Unset or blank keys only fail when
+// Required; keys without a rule are ignored.
+func (s Schema) Validate(values map[string]string) error {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs SchemaError
+	for _, k := range keys {
+		v := values[k]
+		if strings.TrimSpace(v) == "" {
+			if s[k].Required {
+				errs = append(errs, Violation{k, "required"})
+			}
+			continue
+		}
+		if msg := s[k].check(v); msg != "" {
+			errs = append(errs, Violation{k, msg})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check returns why v breaks r, or "".
+func (r Rule) check(v string) string {
+	n, err := r.measure(v)
+	if err != nil {
+		return fmt.Sprintf("invalid %s %q", r.Kind, v)
+	}
+	if len(r.Enum) > 0 && !slices.Contains(r.Enum, v) {
+		return fmt.Sprintf("%q is not one of %s", v, strings.Join(r.Enum, ", "))
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return fmt.Sprintf("bad pattern %q: %v", r.Pattern, err)
+		}
+		if !re.MatchString(v) {
+			return fmt.Sprintf("%q does not match %s", v, r.Pattern)
+		}
+	}
+	if r.Min != nil && n < *r.Min {
+		return fmt.Sprintf("%q is below the minimum %v", v, *r.Min)
+	}
+	if r.Max != nil && n > *r.Max {
+		return fmt.Sprintf("%q is above the maximum %v", v, *r.Max)
+	}
+	return ""
+}
+
+// measure parses v as r.Kind and returns the number Min and Max apply to.
+func (r Rule) measure(v string) (float64, error) {
+	s := strings.TrimSpace(v)
+	switch r.Kind {
+	case KindInt:
+		i, err := parseInt(s, 64)
+		return float64(i), err
+	case KindFloat:
+		return strconv.ParseFloat(s, 64)
+	case KindBool:
+		_, err := strconv.ParseBool(s)
+		return 0, err
+	case KindDuration:
+		d, err := time.ParseDuration(s)
+		return d.Seconds(), err
+	case KindString:
+		return float64(utf8.RuneCountInString(v)), nil
+	}
+	return 0, fmt.Errorf("unknown kind %q", r.Kind)
+}
@@ -0,0 +1,74 @@
+package env
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+// Scope reads keys under a fixed prefix; see WithPrefix.
+type Scope struct {
+	prefix string
+}
+
+// WithPrefix returns a Scope whose getters prepend prefix to every key, so
+// WithPrefix("MYAPP_").String("PORT") reads MYAPP_PORT.
+func WithPrefix(prefix string) Scope { return Scope{prefix: prefix} }
+
+// WithPrefix returns a nested Scope: s's prefix followed by prefix.
+func (s Scope) WithPrefix(prefix string) Scope { return Scope{prefix: s.prefix + prefix} }
+
+// Key returns the full variable name for key.
+func (s Scope) Key(key string) string { return s.prefix + key }
+
+func (s Scope) String(key string, def ...string) string    { return String(s.prefix+key, def...) }
+func (s Scope) Get(key string, def ...string) string       { return Get(s.prefix+key, def...) }
+func (s Scope) Int(key string, def ...int) int             { return Int(s.prefix+key, def...) }
+func (s Scope) Int64(key string, def ...int64) int64       { return Int64(s.prefix+key, def...) }
+func (s Scope) Bool(key string, def ...bool) bool          { return Bool(s.prefix+key, def...) }
+func (s Scope) Truthy(key string, def bool) bool           { return Truthy(s.prefix+key, def) }
+func (s Scope) Count(key string, def ...int64) int64       { return Count(s.prefix+key, def...) }
+func (s Scope) Float64(key string, def ...float64) float64 { return Float64(s.prefix+key, def...) }
+func (s Scope) Uint(key string, def ...uint) uint          { return Uint(s.prefix+key, def...) }
+func (s Scope) Bytes(key string, def ...int64) int64       { return Bytes(s.prefix+key, def...) }
+
+func (s Scope) Duration(key string, def ...time.Duration) time.Duration {
+	return Duration(s.prefix+key, def...)
+}
+
+func (s Scope) FeatureSet(key string) map[string]bool { return FeatureSet(s.prefix + key) }
+
+func (s Scope) StringsEscaped(key, sep string, def []string) []string {
+	return StringsEscaped(s.prefix+key, sep, def)
+}
+
+func (s Scope) Map(key, sep, kvSep string, def map[string]string) map[string]string {
+	return Map(s.prefix+key, sep, kvSep, def)
+}
+
+func (s Scope) MapInt(key, sep, kvSep string, def map[string]int) map[string]int {
+	return MapInt(s.prefix+key, sep, kvSep, def)
+}
+
+func (s Scope) JSON(key string, target any) error { return JSON(s.prefix+key, target) }
+
+// Require is Require with every key prefixed.
+func (s Scope) Require(keys ...string) error {
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = s.prefix + k
+	}
+	return Require(full...)
+}
+
+// Unmarshal is UnmarshalWithPrefix using the scope's prefix.
+func (s Scope) Unmarshal(v any) error { return UnmarshalWithPrefix(s.prefix, v) }
+
+func (s Scope) URL(key string, def ...*url.URL) *url.URL { return URL(s.prefix+key, def...) }
+func (s Scope) IP(key string, def ...net.IP) net.IP      { return IP(s.prefix+key, def...) }
+func (s Scope) Base64(key string, def ...[]byte) []byte  { return Base64(s.prefix+key, def...) }
+func (s Scope) Hex(key string, def ...[]byte) []byte     { return Hex(s.prefix+key, def...) }
+
+func (s Scope) Time(key, layout string, def ...time.Time) time.Time {
+	return Time(s.prefix+key, layout, def...)
+}
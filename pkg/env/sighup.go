@@ -0,0 +1,45 @@
+package env
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ReloadOnSIGHUP loads files like LoadFiles, then reloads them each time the
+// process receives SIGHUP, the usual "re-read your config" signal. Reloads
+// follow the same rules as Watch, and onChange, if non-nil, receives each
+// non-empty delta. A reload that fails to parse is skipped. Call stop, which
+// is safe to call more than once, to stop listening. Only the initial load's
+// error is returned.
+func ReloadOnSIGHUP(files []string, opts *Options, onChange func(changed map[string]string)) (stop func(), err error) {
+	r, err := newReloader(files, opts)
+	if err != nil {
+		return nil, err
+	}
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+			}
+			delta, err := r.reload()
+			if err == nil && len(delta) > 0 && onChange != nil {
+				onChange(delta)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+		})
+	}, nil
+}
@@ -0,0 +1,40 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Snapshot returns a copy of the whole process environment, for Restore.
+func Snapshot() map[string]string {
+	out := map[string]string{}
+	for _, kv := range os.Environ() {
+		// search from 1: Windows has hidden "=C:=C:\dir" entries
+		if i := strings.Index(kv[min(1, len(kv)):], "="); i >= 0 {
+			out[kv[:i+1]] = kv[i+2:]
+		}
+	}
+	return out
+}
+
+// Restore makes the process environment equal to snap: variables not in it
+// are unset and the rest are set to their snapshot values.
+func Restore(snap map[string]string) error {
+	for k := range Snapshot() {
+		if _, keep := snap[k]; !keep {
+			if err := os.Unsetenv(k); err != nil {
+				return fmt.Errorf("unsetenv %q: %w", k, err)
+			}
+		}
+	}
+	for k, v := range snap {
+		if cur, ok := os.LookupEnv(k); ok && cur == v {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("setenv %q: %w", k, err)
+		}
+	}
+	return nil
+}
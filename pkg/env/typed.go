@@ -0,0 +1,51 @@
+package env
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// LoadTyped is LoadFiles returning values as any. With Options.InferTypes,
+// unquoted values are converted: integers to int, other numbers to float64,
+// true/false to bool and JSON objects or arrays to map[string]any or []any.
+// Quoted values and anything else stay strings. The process environment is
+// set exactly as by LoadFiles.
+func LoadTyped(paths []string, opts *Options) (map[string]any, error) {
+	res, err := LoadFilesResult(paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	infer := opts != nil && opts.InferTypes
+	out := make(map[string]any, len(res.Values))
+	for k, v := range res.Values {
+		if infer && !res.quoted[k] {
+			out[k] = inferType(v)
+		} else {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func inferType(v string) any {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(v); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !strings.ContainsAny(v, "xXnN") {
+		return f // skip hex floats, NaN and Inf
+	}
+	if strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[") {
+		var x any
+		if err := json.Unmarshal([]byte(v), &x); err == nil {
+			return x
+		}
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package env
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// URL reads an absolute URL; it must have a scheme and, except for file
+// URLs, a host. Invalid values fall back to def, or panic without one.
+func URL(key string, def ...*url.URL) *url.URL {
+	if v, ok := lookupEnv(key); ok {
+		u, err := url.Parse(strings.TrimSpace(v))
+		if err == nil && u.Scheme != "" && (u.Host != "" || u.Scheme == "file") {
+			return u
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+// IP reads an IPv4 or IPv6 address. Invalid values fall back to def, or
+// panic without one.
+func IP(key string, def ...net.IP) net.IP {
+	if v, ok := lookupEnv(key); ok {
+		if ip := net.ParseIP(strings.TrimSpace(v)); ip != nil {
+			return ip
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+// Time reads a time in layout, time.RFC3339 when layout is empty. Invalid
+// values fall back to def, or panic without one.
+func Time(key, layout string, def ...time.Time) time.Time {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if v, ok := lookupEnv(key); ok {
+		if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+			return t
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+// Base64 reads base64-encoded bytes, standard or URL-safe, with or without
+// padding. Invalid values fall back to def, or panic without one.
+func Base64(key string, def ...[]byte) []byte {
+	if v, ok := lookupEnv(key); ok {
+		v = strings.TrimSpace(v)
+		for _, enc := range base64Encodings {
+			if b, err := enc.DecodeString(v); err == nil {
+				return b
+			}
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+}
+
+// Hex reads hex-encoded bytes. Invalid values fall back to def, or panic
+// without one.
+func Hex(key string, def ...[]byte) []byte {
+	if v, ok := lookupEnv(key); ok {
+		if b, err := hex.DecodeString(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	panic("missing env: " + key)
+}
@@ -0,0 +1,211 @@
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal fills the struct pointed to by v from the environment using
+// `env:"KEY,default=x,required"` field tags. Supported field types are
+// strings, ints, uints, floats, bools, time.Duration, url.URL, any type
+// implementing encoding.TextUnmarshaler (such as time.Time, as RFC 3339),
+// slices of those (comma-separated) and nested structs. A nested struct field with a tag
+// prefixes its fields' keys with KEY_; without one its fields are read as if
+// they were declared inline. Untagged fields are ignored. An empty value
+// counts as unset. Every missing required key is reported in one error.
+func Unmarshal(v any) error {
+	return UnmarshalWithPrefix("", v)
+}
+
+// UnmarshalWithPrefix is Unmarshal with prefix prepended to every key, e.g.
+// "APP_" to read APP_PORT for `env:"PORT"`.
+func UnmarshalWithPrefix(prefix string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("env: Unmarshal needs a non-nil struct pointer")
+	}
+	var miss []string
+	if err := decodeStruct(rv.Elem(), prefix, &miss); err != nil {
+		return err
+	}
+	if len(miss) > 0 {
+		return errors.New("missing env: " + strings.Join(miss, ", "))
+	}
+	return nil
+}
+
+type fieldTag struct {
+	key      string
+	def      string
+	hasDef   bool
+	required bool
+}
+
+// parseTag splits `KEY,default=a,b,required`. Everything after default= up
+// to a later "required" belongs to the default, so defaults may hold commas.
+func parseTag(tag string) fieldTag {
+	parts := strings.Split(tag, ",")
+	t := fieldTag{key: parts[0]}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "required":
+			t.required = true
+		case strings.HasPrefix(p, "default="):
+			t.def, t.hasDef = strings.TrimPrefix(p, "default="), true
+		case t.hasDef:
+			t.def += "," + p
+		}
+	}
+	return t
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isText reports whether values of t are decoded from a single string
+// rather than, for structs, treated as a nested config section.
+func isText(t reflect.Type) bool {
+	return t == urlType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func decodeStruct(sv reflect.Value, prefix string, miss *[]string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, tagged := sf.Tag.Lookup("env")
+		if tag == "-" {
+			continue
+		}
+		fv := sv.Field(i)
+		if p, ok := nestedPrefix(sf, prefix); ok {
+			if err := decodeStruct(fv, p, miss); err != nil {
+				return err
+			}
+			continue
+		}
+		if !tagged {
+			continue
+		}
+		t := parseTag(tag)
+		key := prefix + t.key
+		raw, ok := lookupEnv(key)
+		if !ok || raw == "" {
+			switch {
+			case t.hasDef:
+				raw = t.def
+			case t.required:
+				*miss = append(*miss, key)
+				continue
+			default:
+				continue
+			}
+		}
+		if err := setField(fv, raw); err != nil {
+			return fmt.Errorf("env: %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// nestedPrefix reports whether sf is a nested config struct and, if so,
+// the key prefix for its fields.
+func nestedPrefix(sf reflect.StructField, prefix string) (string, bool) {
+	if sf.Type.Kind() != reflect.Struct || isText(sf.Type) {
+		return "", false
+	}
+	if k := parseTag(sf.Tag.Get("env")).key; k != "" {
+		prefix += k + "_"
+	}
+	return prefix, true
+}
+
+func setField(fv reflect.Value, raw string) error {
+	if fv.Type() == urlType {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	if isText(fv.Type()) && fv.CanAddr() {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(strings.TrimSpace(raw)))
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := parseInt(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := parseUint(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		s := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setField(s.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// GetAs reads key converted to T, which may be any type Unmarshal supports
+// (strings, ints, uints, floats, bools, time.Duration, url.URL,
+// encoding.TextUnmarshaler implementations and slices of those).
+// Unset, empty or malformed values return def. (Get already names the
+// plain string getter, hence the suffix.)
+func GetAs[T any](key string, def T) T {
+	raw, ok := lookupEnv(key)
+	if !ok || raw == "" {
+		return def
+	}
+	var out T
+	if err := setField(reflect.ValueOf(&out).Elem(), raw); err != nil {
+		return def
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// watchInterval is how often Watch polls; tests shorten it.
+var watchInterval = time.Second
+
+// Watch loads files like LoadFiles, then polls them until ctx is done and
+// reloads whenever one changes. fn receives the delta: added and changed
+// keys with their new values, removed keys with "". Variables Watch set
+// itself are updated on reload (removed ones are left in place); ones that
+// were already in the process environment still win unless Overwrite is
+// set. A reload that fails to parse is skipped until the next change. Only
+// the initial load's error is returned; fn must not be nil.
+func Watch(ctx context.Context, files []string, opts *Options, fn func(changed map[string]string)) error {
+	if fn == nil {
+		return errors.New("env: Watch needs a callback")
+	}
+	r, err := newReloader(files, opts)
+	if err != nil {
+		return err
+	}
+	stamps := fileStamps(r.files)
+
+	go func() {
+		t := time.NewTicker(watchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			cur := fileStamps(r.files)
+			if cur == stamps {
+				continue
+			}
+			stamps = cur
+			delta, err := r.reload()
+			if err != nil {
+				continue // retry after the next change
+			}
+			if len(delta) > 0 {
+				fn(delta)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloader re-applies a set of files, tracking which variables it set so
+// later reloads may update them.
+type reloader struct {
+	files []string
+	o     Options
+	prev  map[string]string
+}
+
+// newReloader performs the initial load.
+func newReloader(files []string, opts *Options) (*reloader, error) {
+	r := &reloader{}
+	if opts != nil {
+		r.o = *opts
+	}
+	r.o.owned = map[string]bool{}
+	r.files = filenamesOrDefault(files, &r.o)
+
+	res, err := LoadFilesResult(r.files, &r.o)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range res.Applied {
+		r.o.owned[k] = true
+	}
+	r.prev = res.Values
+	return r, nil
+}
+
+// reload re-reads the files, applies added and changed keys and returns the
+// delta (removed keys map to ""). On error nothing is applied.
+func (r *reloader) reload() (map[string]string, error) {
+	values, order, _, err := resolveFiles(r.files, &r.o)
+	if err != nil {
+		return nil, err
+	}
+	added, removed, changed := Compare(r.prev, values)
+	r.prev = values
+	if len(added)+len(removed)+len(changed) == 0 {
+		return nil, nil
+	}
+	for _, k := range order {
+		if _, ok := added[k]; !ok {
+			if _, ok := changed[k]; !ok {
+				continue
+			}
+		}
+		if r.o.osWins(k) {
+			continue
+		}
+		_, set := os.LookupEnv(k)
+		if os.Setenv(k, values[k]) != nil {
+			continue
+		}
+		r.o.owned[k] = true
+		if r.o.OnSet != nil {
+			r.o.OnSet(k, values[k], set)
+		}
+	}
+
+	delta := make(map[string]string, len(added)+len(removed)+len(changed))
+	for k := range removed {
+		delta[k] = ""
+	}
+	for k, v := range added {
+		delta[k] = v
+	}
+	for k, v := range changed {
+		delta[k] = v
+	}
+	return delta, nil
+}
+
+// fileStamps fingerprints files by size and modification time.
+func fileStamps(files []string) string {
+	var b []byte
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			b = info.ModTime().AppendFormat(b, time.RFC3339Nano)
+			b = append(b, ' ')
+			b = strconv.AppendInt(b, info.Size(), 10)
+		}
+		b = append(b, '\n')
+	}
+	return string(b)
+}
@@ -0,0 +1,123 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DiffDirs compares the regular files under a and b by relative path.
+// It returns paths only in a, only in b, and in both with different
+// content, each sorted. Contents are compared by streaming.
+func (s Store) DiffDirs(a, b string) (onlyA, onlyB, differ []string, err error) {
+	rootA, err := s.path(a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rootB, err := s.path(b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	filesA, err := listFiles(rootA)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	filesB, err := listFiles(rootB)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for rel := range filesA {
+		if _, ok := filesB[rel]; !ok {
+			onlyA = append(onlyA, rel)
+			continue
+		}
+		same, err := sameContent(filepath.Join(rootA, rel), filepath.Join(rootB, rel))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !same {
+			differ = append(differ, rel)
+		}
+	}
+	for rel := range filesB {
+		if _, ok := filesA[rel]; !ok {
+			onlyB = append(onlyB, rel)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	sort.Strings(differ)
+	return onlyA, onlyB, differ, nil
+}
+
+// listFiles returns the relative paths of regular files under root.
+func listFiles(root string) (map[string]struct{}, error) {
+	out := map[string]struct{}{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		out[rel] = struct{}{}
+		return nil
+	})
+	return out, err
+}
+
+func sameContent(p1, p2 string) (bool, error) {
+	f1, err := os.Open(p1)
+	if err != nil {
+		return false, err
+	}
+	defer f1.Close()
+	f2, err := os.Open(p2)
+	if err != nil {
+		return false, err
+	}
+	defer f2.Close()
+
+	i1, err := f1.Stat()
+	if err != nil {
+		return false, err
+	}
+	i2, err := f2.Stat()
+	if err != nil {
+		return false, err
+	}
+	if i1.Size() != i2.Size() {
+		return false, nil
+	}
+
+	r1, r2 := bufio.NewReader(f1), bufio.NewReader(f2)
+	b1 := make([]byte, 32*1024)
+	b2 := make([]byte, 32*1024)
+	for {
+		n1, err1 := io.ReadFull(r1, b1)
+		n2, err2 := io.ReadFull(r2, b2)
+		if !bytes.Equal(b1[:n1], b2[:n2]) {
+			return false, nil
+		}
+		end1 := err1 == io.EOF || err1 == io.ErrUnexpectedEOF
+		end2 := err2 == io.EOF || err2 == io.ErrUnexpectedEOF
+		if end1 || end2 {
+			return end1 && end2, nil
+		}
+		if err1 != nil {
+			return false, err1
+		}
+		if err2 != nil {
+			return false, err2
+		}
+	}
+}
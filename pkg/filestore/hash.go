@@ -0,0 +1,120 @@
+package filestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// HashDir returns a hex sha256 fingerprint of every regular file under dir,
+// covering relative path, mode and content. Walk order does not matter, so
+// identical trees always produce the same hash.
+func (s Store) HashDir(dir string) (string, error) {
+	root, err := s.path(dir)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s\x00%o\x00%s\n", filepath.ToSlash(rel), info.Mode().Perm(), sum))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, l := range lines {
+		io.WriteString(h, l)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteReaderHashed streams r into p while feeding it through h, returning
+// h's sum and the byte count, so a download can be saved and checksummed in
+// one pass. h is not reset first. With StoreOptions.Atomic the file only
+// appears once fully written.
+func (s Store) WriteReaderHashed(p string, r io.Reader, h hash.Hash, opts ...WriteOptions) (sum []byte, n int64, err error) {
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), s.dirPerm()); err != nil {
+		return nil, 0, err
+	}
+	perm := s.filePerm(opts)
+
+	name := abs
+	var f *os.File
+	if s.opts.Atomic {
+		f, err = os.CreateTemp(filepath.Dir(abs), "."+filepath.Base(abs)+".tmp-*")
+		if err == nil {
+			name = f.Name()
+			defer os.Remove(name) // no-op after a successful rename
+			err = f.Chmod(perm)
+		}
+	} else {
+		f, err = os.OpenFile(abs, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return nil, 0, err
+	}
+
+	n, err = io.Copy(f, io.TeeReader(r, h))
+	if err == nil && name != abs {
+		err = f.Sync() // flush before the rename, as writeAtomic does
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, n, err
+	}
+	if name != abs {
+		if err := os.Rename(name, abs); err != nil {
+			return nil, n, err
+		}
+	}
+	return h.Sum(nil), n, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,41 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReadJSONL decodes a JSON Lines file into a slice, skipping blank lines.
+// Decode errors include the 1-based line number.
+func ReadJSONL[T any](s Store, p string) ([]T, error) {
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []T
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", p, n, err)
+		}
+		out = append(out, v)
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,59 @@
+package filestore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/SamuelDBines/go-helpers/pkg/glob"
+)
+
+// RemoveOptions configures RemoveMatching.
+type RemoveOptions struct {
+	Dirs bool // also remove matching directories, recursively
+}
+
+// RemoveMatching deletes every path under Root matching pattern (see
+// glob.MatchPattern; patterns are relative to Root with forward slashes) and
+// returns the removed paths relative to Root. Matching directories are
+// skipped unless RemoveOptions.Dirs is set.
+func (s Store) RemoveMatching(pattern string, opts ...RemoveOptions) (removed []string, err error) {
+	dirs := len(opts) > 0 && opts[0].Dirs
+	root, err := s.path(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if !glob.MatchPattern(pattern, filepath.ToSlash(rel)) || d.IsDir() && !dirs {
+			return nil
+		}
+		matches = append(matches, rel)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range matches {
+		if err := os.RemoveAll(filepath.Join(root, rel)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, rel)
+	}
+	return removed, nil
+}
@@ -0,0 +1,50 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Rotate rotates p once it exceeds maxBytes: p.1 becomes p.2 and so on up to
+// p.<keep> (the oldest is dropped), p becomes p.1 and an empty p is created
+// with the same mode. A missing or small enough file is left alone. keep
+// must be at least 1.
+func (s Store) Rotate(p string, maxBytes int64, keep int) error {
+	if keep < 1 {
+		return fmt.Errorf("filestore: Rotate needs keep >= 1, got %d", keep)
+	}
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() <= maxBytes {
+		return nil
+	}
+
+	backup := func(i int) string { return fmt.Sprintf("%s.%d", abs, i) }
+	if err := os.Remove(backup(keep)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(abs, backup(1)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
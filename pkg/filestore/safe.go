@@ -0,0 +1,80 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrOutsideRoot is returned when a path would resolve outside Store.Root.
+var ErrOutsideRoot = errors.New("filestore: path escapes root")
+
+// Join cleans and joins parts into a path relative to Root, failing with
+// ErrOutsideRoot if the result would leave Root. Use it to build paths from
+// untrusted input before calling Read/Write.
+func (s Store) Join(parts ...string) (string, error) {
+	return s.local(filepath.Join(parts...))
+}
+
+// local returns p as a clean path relative to Root, or ErrOutsideRoot.
+// Absolute paths are accepted only if they lie under Root.
+func (s Store) local(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		rootAbs, err := filepath.Abs(s.Root)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(rootAbs, p)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrOutsideRoot, p)
+		}
+		p = rel
+	}
+	p = filepath.Clean(p)
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, p)
+	}
+	return p, nil
+}
+
+// Symlink creates newname as a symlink to oldname, both relative to Root.
+// The link is stored relative to its own directory so the tree can be moved.
+// Both ends must stay within Root. On Windows this needs Developer Mode or
+// admin rights; otherwise the error from os.Symlink is returned.
+func (s Store) Symlink(oldname, newname string) error {
+	oldRel, err := s.local(oldname)
+	if err != nil {
+		return err
+	}
+	newRel, err := s.local(newname)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(newRel), oldRel)
+	if err != nil {
+		return err
+	}
+	newAbs := filepath.Join(s.Root, newRel)
+	if err := os.MkdirAll(filepath.Dir(newAbs), s.dirPerm()); err != nil {
+		return err
+	}
+	return os.Symlink(target, newAbs)
+}
+
+// Readlink returns the target of the symlink at p as a path relative to
+// Root. Targets outside Root yield ErrOutsideRoot.
+func (s Store) Readlink(p string) (string, error) {
+	rel, err := s.local(p)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(filepath.Join(s.Root, rel))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(rel), target)
+	}
+	return s.local(target)
+}
@@ -1,26 +1,47 @@
 package filestore
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Store struct {
 	Root string
+	opts StoreOptions
 }
 
 type WriteOptions struct {
 	Perm fs.FileMode // file perm, default 0644
 }
 
+// StoreOptions sets store-wide defaults; see NewWithOptions.
+type StoreOptions struct {
+	DefaultPerm    fs.FileMode // file perm, default 0644
+	DefaultDirPerm fs.FileMode // perm for created dirs, default 0755
+	Atomic         bool        // Write via a temp file + rename
+	SafePaths      bool        // reject paths escaping Root with ErrOutsideRoot
+
+	// ReadFile replaces os.ReadFile for Read and the helpers built on it,
+	// e.g. to add tracing or simulate a slow filesystem.
+	ReadFile func(name string) ([]byte, error)
+}
+
 func New(root string) Store {
 	return Store{Root: root}
 }
 
+// NewWithOptions returns a Store whose methods use opts as defaults.
+func NewWithOptions(root string, opts StoreOptions) Store {
+	return Store{Root: root, opts: opts}
+}
+
 // Abs resolves a path under Root (unless already absolute).
 func (s Store) Abs(p string) string {
 	if filepath.IsAbs(p) {
@@ -29,22 +50,102 @@ func (s Store) Abs(p string) string {
 	return filepath.Join(s.Root, p)
 }
 
-// EnsureDirForFile creates parent dirs for a file path.
+// path resolves p like Abs, applying the traversal guard when SafePaths is set.
+func (s Store) path(p string) (string, error) {
+	if !s.opts.SafePaths {
+		return s.Abs(p), nil
+	}
+	rel, err := s.local(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Root, rel), nil
+}
+
+func (s Store) filePerm(opts []WriteOptions) fs.FileMode {
+	if len(opts) > 0 && opts[0].Perm != 0 {
+		return opts[0].Perm
+	}
+	if s.opts.DefaultPerm != 0 {
+		return s.opts.DefaultPerm
+	}
+	return 0644
+}
+
+func (s Store) dirPerm() fs.FileMode {
+	if s.opts.DefaultDirPerm != 0 {
+		return s.opts.DefaultDirPerm
+	}
+	return 0755
+}
+
+// EnsureDirForFile creates parent dirs for a file path. A zero perm uses the
+// store default.
 func (s Store) EnsureDirForFile(p string, perm fs.FileMode) error {
-	abs := s.Abs(p)
-	dir := filepath.Dir(abs)
-	return os.MkdirAll(dir, perm)
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = s.dirPerm()
+	}
+	return os.MkdirAll(filepath.Dir(abs), perm)
 }
 
 // Exists checks whether a file/dir exists.
 func (s Store) Exists(p string) bool {
-	_, err := os.Stat(s.Abs(p))
+	abs, err := s.path(p)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(abs)
 	return err == nil
 }
 
 // Read reads full file contents.
 func (s Store) Read(p string) ([]byte, error) {
-	return os.ReadFile(s.Abs(p))
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.ReadFile != nil {
+		return s.opts.ReadFile(abs)
+	}
+	return os.ReadFile(abs)
+}
+
+// ErrTooLarge is returned by ReadLimit when a file exceeds the limit.
+var ErrTooLarge = errors.New("filestore: file too large")
+
+// ReadLimit reads a file of at most max bytes, returning ErrTooLarge
+// otherwise. The limit is checked via Stat and enforced again while reading,
+// in case the file grows in between.
+func (s Store) ReadLimit(p string, max int64) ([]byte, error) {
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		return nil, ErrTooLarge
+	}
+	b, err := io.ReadAll(io.LimitReader(f, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > max {
+		return nil, ErrTooLarge
+	}
+	return b, nil
 }
 
 // ReadString reads file as string.
@@ -56,16 +157,67 @@ func (s Store) ReadString(p string) (string, error) {
 	return string(b), nil
 }
 
+// Head returns up to the first n lines of a file without reading the rest.
+func (s Store) Head(p string, n int) ([]string, error) {
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	sc := bufio.NewScanner(f)
+	for len(out) < n && sc.Scan() {
+		out = append(out, sc.Text())
+	}
+	return out, sc.Err()
+}
+
 // Write writes bytes to file, creating parent dirs.
+// With StoreOptions.Atomic the data is written to a temp file and renamed
+// into place, so readers never observe a partial file.
 func (s Store) Write(p string, data []byte, opts ...WriteOptions) error {
-	perm := fs.FileMode(0644)
-	if len(opts) > 0 && opts[0].Perm != 0 {
-		perm = opts[0].Perm
+	abs, err := s.path(p)
+	if err != nil {
+		return err
 	}
-	if err := s.EnsureDirForFile(p, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(abs), s.dirPerm()); err != nil {
 		return err
 	}
-	return os.WriteFile(s.Abs(p), data, perm)
+	if s.opts.Atomic {
+		return writeAtomic(abs, data, s.filePerm(opts))
+	}
+	return os.WriteFile(abs, data, s.filePerm(opts))
+}
+
+// writeAtomic writes data to a temp file beside abs and renames it over abs.
+func writeAtomic(abs string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(abs), "."+filepath.Base(abs)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), abs)
 }
 
 // WriteString writes a string.
@@ -73,18 +225,23 @@ func (s Store) WriteString(p string, data string, opts ...WriteOptions) error {
 	return s.Write(p, []byte(data), opts...)
 }
 
+// WriteText writes text ending in exactly one newline, adding one if missing
+// and collapsing any run of trailing newlines.
+func (s Store) WriteText(p string, text string, opts ...WriteOptions) error {
+	return s.WriteString(p, strings.TrimRight(text, "\r\n")+"\n", opts...)
+}
+
 // Append appends bytes to a file (creates file + parent dirs if needed).
 func (s Store) Append(p string, data []byte, opts ...WriteOptions) error {
-	perm := fs.FileMode(0644)
-	if len(opts) > 0 && opts[0].Perm != 0 {
-		perm = opts[0].Perm
+	abs, err := s.path(p)
+	if err != nil {
+		return err
 	}
-
-	if err := s.EnsureDirForFile(p, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(abs), s.dirPerm()); err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(s.Abs(p), os.O_CREATE|os.O_WRONLY|os.O_APPEND, perm)
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_WRONLY|os.O_APPEND, s.filePerm(opts))
 	if err != nil {
 		return err
 	}
@@ -101,7 +258,11 @@ func (s Store) AppendString(p string, data string, opts ...WriteOptions) error {
 
 // Delete deletes a file. If missing, it’s a no-op.
 func (s Store) Delete(p string) error {
-	err := os.Remove(s.Abs(p))
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(abs)
 	if errors.Is(err, os.ErrNotExist) {
 		return nil
 	}
@@ -110,7 +271,11 @@ func (s Store) Delete(p string) error {
 
 // DeleteDir deletes a directory recursively. If missing, no-op.
 func (s Store) DeleteDir(p string) error {
-	err := os.RemoveAll(s.Abs(p))
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(abs)
 	if errors.Is(err, os.ErrNotExist) {
 		return nil
 	}
@@ -119,32 +284,111 @@ func (s Store) DeleteDir(p string) error {
 
 // Copy copies file from src to dst (creates dst parent dirs).
 func (s Store) Copy(src, dst string, perm fs.FileMode) error {
+	return s.CopyProgress(src, dst, perm, nil)
+}
+
+// CopyProgress is Copy that reports progress: after each chunk it calls
+// progress with the cumulative bytes copied and the source size. A zero-byte
+// file gets a single (0, 0) call. progress may be nil.
+func (s Store) CopyProgress(src, dst string, perm fs.FileMode, progress func(copied, total int64)) error {
 	if perm == 0 {
-		perm = 0644
+		perm = s.filePerm(nil)
 	}
-	in, err := os.Open(s.Abs(src))
+	srcAbs, err := s.path(src)
+	if err != nil {
+		return err
+	}
+	dstAbs, err := s.path(dst)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(srcAbs)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
 
-	if err := s.EnsureDirForFile(dst, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(dstAbs), s.dirPerm()); err != nil {
 		return err
 	}
 
-	out, err := os.OpenFile(s.Abs(dst), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	out, err := os.OpenFile(dstAbs, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, in)
-	return err
+	if progress == nil {
+		_, err = io.Copy(out, in)
+		return err
+	}
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	pw := &progressWriter{w: out, total: info.Size(), fn: progress}
+	if _, err := io.Copy(pw, in); err != nil {
+		return err
+	}
+	if pw.copied == 0 {
+		progress(0, pw.total)
+	}
+	return nil
+}
+
+type progressWriter struct {
+	w      io.Writer
+	copied int64
+	total  int64
+	fn     func(copied, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.copied += int64(n)
+	if n > 0 {
+		p.fn(p.copied, p.total)
+	}
+	return n, err
+}
+
+// Move renames src to dst, creating dst parent dirs.
+func (s Store) Move(src, dst string) error {
+	srcAbs, err := s.path(src)
+	if err != nil {
+		return err
+	}
+	dstAbs, err := s.path(dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstAbs), s.dirPerm()); err != nil {
+		return err
+	}
+	return os.Rename(srcAbs, dstAbs)
+}
+
+// MoveInto moves src into dstDir keeping its base name (like `mv file dir/`),
+// creating dstDir if needed, and returns the new path. An existing file at
+// the destination is replaced only if overwrite is set.
+func (s Store) MoveInto(src, dstDir string, overwrite bool) (string, error) {
+	dst := filepath.Join(dstDir, filepath.Base(src))
+	if !overwrite && s.Exists(dst) {
+		return "", &fs.PathError{Op: "move", Path: dst, Err: fs.ErrExist}
+	}
+	if err := s.Move(src, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
 }
 
 // ListDir returns entry names in a directory.
 func (s Store) ListDir(dir string) ([]string, error) {
-	entries, err := os.ReadDir(s.Abs(dir))
+	abs, err := s.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(abs)
 	if err != nil {
 		return nil, err
 	}
@@ -157,7 +401,10 @@ func (s Store) ListDir(dir string) ([]string, error) {
 
 // Walk walks files under a directory.
 func (s Store) Walk(dir string, fn func(rel string, d fs.DirEntry) error) error {
-	root := s.Abs(dir)
+	root, err := s.path(dir)
+	if err != nil {
+		return err
+	}
 	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -186,24 +433,57 @@ func (s Store) WriteJSON(p string, v any, pretty bool) error {
 		return err
 	}
 	b = append(b, '\n')
-	return s.Write(p, b, WriteOptions{Perm: 0644})
+	return s.Write(p, b)
 }
 
+// ReadJSON decodes p into out. A zero-byte file counts as no data: out is
+// left untouched and nil returned. Whitespace-only content is still invalid.
 func (s Store) ReadJSON(p string, out any) error {
 	b, err := s.Read(p)
 	if err != nil {
 		return err
 	}
+	return unmarshalJSON(b, out)
+}
+
+func unmarshalJSON(b []byte, out any) error {
+	if len(b) == 0 {
+		return nil
+	}
 	return json.Unmarshal(b, out)
 }
 
+// ReadJSONCtx is ReadJSON that gives up when ctx is done, returning ctx.Err().
+// The underlying read is abandoned, not interrupted; out is left untouched.
+func (s Store) ReadJSONCtx(ctx context.Context, p string, out any) error {
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1) // buffered so an abandoned read can finish
+	go func() {
+		b, err := s.Read(p)
+		ch <- result{b, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-ch:
+		if res.err != nil {
+			return res.err
+		}
+		return unmarshalJSON(res.b, out)
+	}
+}
+
 // --- “format” helpers (no parsing libs) ---
 
 func (s Store) WriteYAML(p string, yamlText string) error {
 	if len(yamlText) == 0 || yamlText[len(yamlText)-1] != '\n' {
 		yamlText += "\n"
 	}
-	return s.WriteString(p, yamlText, WriteOptions{Perm: 0644})
+	return s.WriteString(p, yamlText)
 }
 
 func (s Store) ReadYAML(p string) (string, error) {
@@ -211,5 +491,5 @@ func (s Store) ReadYAML(p string) (string, error) {
 }
 
 func (s Store) WriteHTML(p string, html string) error {
-	return s.WriteString(p, html, WriteOptions{Perm: 0644})
+	return s.WriteString(p, html)
 }
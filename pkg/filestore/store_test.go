@@ -0,0 +1,626 @@
+package filestore
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	old := watchInterval
+	watchInterval = 10 * time.Millisecond
+	defer func() { watchInterval = old }()
+
+	s := New(t.TempDir())
+	if err := s.WriteString("conf.txt", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	fired := make(chan struct{}, 8)
+	stop, err := s.WatchFile("conf.txt", func() { fired <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.WriteString("conf.txt", "ab"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-fired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected callback after change")
+	}
+
+	stop()
+	stop() // idempotent
+
+	if err := s.WriteString("conf.txt", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-fired:
+		t.Fatal("callback fired after stop")
+	default:
+	}
+
+	if _, err := s.WatchFile("missing.txt", func() {}); err == nil {
+		t.Fatal("expected error watching missing file")
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	s := New(t.TempDir())
+	for _, dir := range []string{"a", "b"} {
+		if err := s.WriteString(dir+"/x.txt", "hello"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteString(dir+"/sub/y.txt", "world"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ha, err := s.HashDir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb, err := s.HashDir("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha != hb {
+		t.Fatalf("identical trees hashed differently: %s vs %s", ha, hb)
+	}
+
+	if err := s.WriteString("b/sub/y.txt", "worle"); err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ = s.HashDir("b"); hb == ha {
+		t.Fatal("content change did not change hash")
+	}
+
+	if err := s.WriteString("b/sub/y.txt", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(s.Abs("b/x.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ = s.HashDir("b"); hb == ha {
+		t.Fatal("mode change did not change hash")
+	}
+}
+
+func TestHead(t *testing.T) {
+	s := New(t.TempDir())
+	var big strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&big, "line %d\n", i)
+	}
+	if err := s.WriteString("big.txt", big.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteString("one.txt", "only"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Head("big.txt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"line 0", "line 1", "line 2"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	got, err = s.Head("one.txt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"only"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	s := New(t.TempDir())
+	for _, in := range []string{"a: 1", "a: 1\n", "a: 1\n\n\n", "a: 1\r\n\r\n"} {
+		if err := s.WriteText("f.txt", in); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := s.ReadString("f.txt")
+		if got != "a: 1\n" {
+			t.Fatalf("WriteText(%q) wrote %q", in, got)
+		}
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	type rec struct {
+		ID int `json:"id"`
+	}
+	s := New(t.TempDir())
+	if err := s.WriteString("ok.jsonl", "{\"id\":1}\n\n{\"id\":2}\n"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadJSONL[rec](s, "ok.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []rec{{1}, {2}}) {
+		t.Fatalf("got %v", got)
+	}
+
+	if err := s.WriteString("bad.jsonl", "{\"id\":1}\n{\"id\":\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJSONL[rec](s, "bad.jsonl"); err == nil || !strings.Contains(err.Error(), "bad.jsonl:2:") {
+		t.Fatalf("expected line 2 error, got %v", err)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	s := New(t.TempDir())
+	if p, err := s.Join("users", "u1.json"); err != nil || p != filepath.Join("users", "u1.json") {
+		t.Fatalf("Join clean = %q, %v", p, err)
+	}
+	if p, err := s.Join("users", "../cache", "x"); err != nil || p != filepath.Join("cache", "x") {
+		t.Fatalf("Join in-root .. = %q, %v", p, err)
+	}
+	if _, err := s.Join("users", "../../etc/passwd"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot, got %v", err)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	safe := NewWithOptions(t.TempDir(), StoreOptions{SafePaths: true})
+	if err := safe.WriteString("../escape.txt", "x"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot, got %v", err)
+	}
+	if _, err := safe.Read("/etc/passwd"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot, got %v", err)
+	}
+	if err := safe.WriteString("ok/in.txt", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic := NewWithOptions(t.TempDir(), StoreOptions{Atomic: true, DefaultPerm: 0o600})
+	if err := atomic.WriteString("state.json", "{}"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(atomic.Abs("state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Mode().Perm() != 0o600 {
+		t.Fatalf("perm = %v, want 0600", before.Mode().Perm())
+	}
+	if err := atomic.WriteString("state.json", `{"a":1}`); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(atomic.Abs("state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename replaces the file, so it is a new inode
+	if os.SameFile(before, after) {
+		t.Fatal("atomic write did not replace the file via rename")
+	}
+	if names, _ := atomic.ListDir("."); len(names) != 1 {
+		t.Fatalf("temp files left behind: %v", names)
+	}
+}
+
+func TestZipUnzip(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.WriteString("src/a.txt", "alpha"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteString("src/nested/b.sh", "#!/bin/sh\n", WriteOptions{Perm: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Zip("src", "out/bundle.zip"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unzip("out/bundle.zip", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	ha, _ := s.HashDir("src")
+	hb, _ := s.HashDir("dst")
+	if ha != hb {
+		t.Fatal("unzipped tree differs from source")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("../evil.txt")
+	w.Write([]byte("pwned"))
+	zw.Close()
+	if err := s.Write("evil.zip", buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unzip("evil.zip", "dst2"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot, got %v", err)
+	}
+	if s.Exists("evil.txt") {
+		t.Fatal("zip-slip entry was written")
+	}
+}
+
+func TestDiffDirs(t *testing.T) {
+	s := New(t.TempDir())
+	files := map[string]string{
+		"a/same.txt":     "same",
+		"b/same.txt":     "same",
+		"a/sub/mod.txt":  "old",
+		"b/sub/mod.txt":  "new",
+		"a/removed.txt":  "gone",
+		"b/added/new.md": "hi",
+	}
+	for p, c := range files {
+		if err := s.WriteString(p, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	onlyA, onlyB, differ, err := s.DiffDirs("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(onlyA, []string{"removed.txt"}) ||
+		!slices.Equal(onlyB, []string{filepath.Join("added", "new.md")}) ||
+		!slices.Equal(differ, []string{filepath.Join("sub", "mod.txt")}) {
+		t.Fatalf("onlyA=%v onlyB=%v differ=%v", onlyA, onlyB, differ)
+	}
+
+	onlyA, onlyB, differ, err = s.DiffDirs("a", "a")
+	if err != nil || len(onlyA)+len(onlyB)+len(differ) != 0 {
+		t.Fatalf("identical trees: %v %v %v %v", onlyA, onlyB, differ, err)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need elevated privileges on windows")
+	}
+	s := New(t.TempDir())
+	if err := s.WriteString("releases/v2/app.txt", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Symlink("releases/v2", "current"); err != nil {
+		t.Fatal(err)
+	}
+	target, err := s.Readlink("current")
+	if err != nil || target != filepath.Join("releases", "v2") {
+		t.Fatalf("Readlink = %q, %v", target, err)
+	}
+	if got, err := s.ReadString("current/app.txt"); err != nil || got != "v2" {
+		t.Fatalf("read through link = %q, %v", got, err)
+	}
+
+	if err := s.Symlink("../../etc", "escape"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot for target, got %v", err)
+	}
+	if err := s.Symlink("releases/v2", "../escape"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot for link, got %v", err)
+	}
+	if err := os.Symlink("/etc", s.Abs("raw")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Readlink("raw"); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("expected ErrOutsideRoot reading outside link, got %v", err)
+	}
+}
+
+func TestMoveInto(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.WriteString("inbox/a.txt", "a"); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := s.MoveInto("inbox/a.txt", "archive/2024", false)
+	if err != nil || dst != filepath.Join("archive", "2024", "a.txt") {
+		t.Fatalf("MoveInto new dir = %q, %v", dst, err)
+	}
+	if s.Exists("inbox/a.txt") || !s.Exists(dst) {
+		t.Fatal("file was not moved")
+	}
+
+	if err := s.WriteString("inbox/a.txt", "a2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MoveInto("inbox/a.txt", "archive/2024", false); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected ErrExist, got %v", err)
+	}
+	if _, err := s.MoveInto("inbox/a.txt", "archive/2024", true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.ReadString(dst); got != "a2" {
+		t.Fatalf("overwrite content = %q", got)
+	}
+}
+
+func TestReadJSONCtx(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.WriteJSON("a.json", map[string]int{"n": 1}, false); err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]int
+	if err := s.ReadJSONCtx(context.Background(), "a.json", &out); err != nil || out["n"] != 1 {
+		t.Fatalf("ReadJSONCtx = %v, %v", out, err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	s = NewWithOptions(s.Root, StoreOptions{ReadFile: func(name string) ([]byte, error) {
+		<-release
+		return os.ReadFile(name)
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := s.ReadJSONCtx(ctx, "a.json", &out); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	s := New(t.TempDir())
+	for i := 1; i <= 4; i++ {
+		if err := s.WriteString("app.log", strings.Repeat(strconv.Itoa(i), 20)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Rotate("app.log", 10, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, _ := s.ReadString("app.log"); got != "" {
+		t.Fatalf("app.log not fresh: %q", got)
+	}
+	if got, _ := s.ReadString("app.log.1"); !strings.HasPrefix(got, "4") {
+		t.Fatalf("app.log.1 = %q", got)
+	}
+	if got, _ := s.ReadString("app.log.2"); !strings.HasPrefix(got, "3") {
+		t.Fatalf("app.log.2 = %q", got)
+	}
+	if s.Exists("app.log.3") {
+		t.Fatal("oldest backup was not evicted")
+	}
+
+	if err := s.WriteString("small.log", "tiny"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rotate("small.log", 10, 2); err != nil || s.Exists("small.log.1") {
+		t.Fatalf("small file rotated: %v", err)
+	}
+
+	if err := s.WriteString("big.log", strings.Repeat("x", 20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rotate("big.log", 10, 0); err == nil {
+		t.Fatal("expected error for keep 0")
+	}
+	if got, _ := s.ReadString("big.log"); len(got) != 20 {
+		t.Fatalf("big.log changed on rejected rotate: %q", got)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.WriteString("f.bin", "0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := s.ReadLimit("f.bin", 20); err != nil || string(b) != "0123456789" {
+		t.Fatalf("under limit = %q, %v", b, err)
+	}
+	if b, err := s.ReadLimit("f.bin", 10); err != nil || len(b) != 10 {
+		t.Fatalf("at limit = %q, %v", b, err)
+	}
+	if _, err := s.ReadLimit("f.bin", 9); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestUpdateJSON(t *testing.T) {
+	s := New(t.TempDir())
+	err := s.UpdateJSON("conf/app.json", func(m map[string]any) error {
+		m["name"] = "demo"
+		m["count"] = 0
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.ReadString("conf/app.json"); got != "{\n  \"count\": 0,\n  \"name\": \"demo\"\n}\n" {
+		t.Fatalf("created file = %q", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.UpdateJSON("conf/app.json", func(m map[string]any) error {
+				m["count"] = m["count"].(float64) + 1
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var out struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	if err := s.ReadJSON("conf/app.json", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "demo" || out.Count != 20 {
+		t.Fatalf("after concurrent updates: %+v", out)
+	}
+
+	if err := s.WriteString("empty.json", ""); err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdateJSON("empty.json", func(m map[string]any) error {
+		if len(m) != 0 {
+			t.Errorf("empty file gave %v", m)
+		}
+		m["ok"] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateJSON on empty file: %v", err)
+	}
+	if got, _ := s.ReadString("empty.json"); got != "{\n  \"ok\": true\n}\n" {
+		t.Fatalf("empty.json = %q", got)
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	s := New(t.TempDir())
+	data := bytes.Repeat([]byte("x"), 100<<10) // several io.Copy chunks
+	if err := s.Write("big.bin", data); err != nil {
+		t.Fatal(err)
+	}
+	var calls [][2]int64
+	err := s.CopyProgress("big.bin", "out/big.bin", 0, func(copied, total int64) {
+		calls = append(calls, [2]int64{copied, total})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) == 0 || calls[len(calls)-1] != [2]int64{int64(len(data)), int64(len(data))} {
+		t.Fatalf("final call = %v", calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i][0] < calls[i-1][0] {
+			t.Fatalf("progress not monotonic: %v", calls)
+		}
+	}
+	if got, _ := s.Read("out/big.bin"); !bytes.Equal(got, data) {
+		t.Fatal("copied content differs")
+	}
+
+	s.WriteString("empty", "")
+	calls = nil
+	if err := s.CopyProgress("empty", "empty2", 0, func(c, tot int64) { calls = append(calls, [2]int64{c, tot}) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != [2]int64{0, 0} {
+		t.Fatalf("zero-byte calls = %v", calls)
+	}
+}
+
+func TestWriteReaderHashed(t *testing.T) {
+	data := strings.Repeat("payload ", 10000)
+	want := sha256.Sum256([]byte(data))
+
+	for _, atomic := range []bool{false, true} {
+		s := NewWithOptions(t.TempDir(), StoreOptions{Atomic: atomic})
+		sum, n, err := s.WriteReaderHashed("dl/file.bin", strings.NewReader(data), sha256.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sum, want[:]) || n != int64(len(data)) {
+			t.Fatalf("atomic=%v: sum %x n %d", atomic, sum, n)
+		}
+		if got, _ := s.ReadString("dl/file.bin"); got != data {
+			t.Fatalf("atomic=%v: content differs", atomic)
+		}
+	}
+}
+
+func TestReadJSONEmpty(t *testing.T) {
+	s := New(t.TempDir())
+	s.WriteString("empty.json", "")
+	s.WriteString("blank.json", " \n")
+	s.WriteString("ok.json", `{"n":1}`)
+
+	var v struct{ N int }
+	if err := s.ReadJSON("empty.json", &v); err != nil || v.N != 0 {
+		t.Fatalf("empty file: %+v, %v", v, err)
+	}
+	if err := s.ReadJSON("blank.json", &v); err == nil {
+		t.Fatal("whitespace-only file should fail")
+	}
+	if err := s.ReadJSON("ok.json", &v); err != nil || v.N != 1 {
+		t.Fatalf("valid file: %+v, %v", v, err)
+	}
+}
+
+func TestWithJSON(t *testing.T) {
+	s := New(t.TempDir())
+	type state struct {
+		Count int `json:"count"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var st state
+			if err := s.WithJSON("state.json", &st, func() error { st.Count++; return nil }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	st := state{Count: 99}
+	if err := s.WithJSON("state.json", &st, func() error { return errors.New("abort") }); err == nil {
+		t.Fatal("expected fn error")
+	}
+	if st.Count != 20 {
+		t.Fatalf("count = %d, want 20", st.Count)
+	}
+	if err := s.WithJSON("state.json", st, func() error { return nil }); err == nil {
+		t.Fatal("expected error for non-pointer")
+	}
+}
+
+func TestRemoveMatching(t *testing.T) {
+	s := New(t.TempDir())
+	for _, p := range []string{"a.tmp", "b.tmp", "keep.txt", "cache/c.tmp", "cache/d.txt", "old.tmp/inner.txt"} {
+		if err := s.WriteString(p, "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := s.RemoveMatching("*.tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"a.tmp", "b.tmp"}) {
+		t.Fatalf("removed %q", removed)
+	}
+	if !s.Exists("keep.txt") || !s.Exists("cache/c.tmp") || !s.Exists("old.tmp/inner.txt") {
+		t.Fatal("removed too much")
+	}
+
+	removed, err = s.RemoveMatching("**/*.tmp", RemoveOptions{Dirs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{filepath.Join("cache", "c.tmp"), "old.tmp"}) || s.Exists("old.tmp") {
+		t.Fatalf("removed %q", removed)
+	}
+}
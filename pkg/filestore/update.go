@@ -0,0 +1,98 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// pathLocks serializes read-modify-write cycles on the same file within
+// this process.
+var pathLocks sync.Map // abs path -> *sync.Mutex
+
+func lockPath(abs string) func() {
+	m, _ := pathLocks.LoadOrStore(abs, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// UpdateJSON loads the JSON object at p (empty if the file is missing or
+// empty), lets fn mutate it and atomically writes it back indented.
+// Concurrent updates of the same file in this process are serialized. If
+// fn returns an error nothing is written.
+func (s Store) UpdateJSON(p string, fn func(m map[string]any) error) error {
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	defer lockPath(abs)()
+
+	m := map[string]any{}
+	b, err := os.ReadFile(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := unmarshalJSON(b, &m); err != nil {
+			return err
+		}
+		if m == nil { // file contained null
+			m = map[string]any{}
+		}
+	}
+
+	if err := fn(m); err != nil {
+		return err
+	}
+	return s.writeJSONAtomic(abs, m)
+}
+
+// WithJSON is UpdateJSON for typed state: it decodes p into ptr (reset to
+// its zero value first, so a missing or empty file leaves it zero), runs fn
+// to mutate *ptr and atomically writes the result back. Calls on the same
+// file in this process are serialized; there is no cross-process lock.
+// If fn returns an error nothing is written.
+func (s Store) WithJSON(p string, ptr any, fn func() error) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("filestore: WithJSON needs a non-nil pointer")
+	}
+	abs, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	defer lockPath(abs)()
+
+	rv.Elem().SetZero()
+	b, err := os.ReadFile(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := unmarshalJSON(b, ptr); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+	return s.writeJSONAtomic(abs, ptr)
+}
+
+func (s Store) writeJSONAtomic(abs string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), s.dirPerm()); err != nil {
+		return err
+	}
+	return writeAtomic(abs, append(b, '\n'), s.filePerm(nil))
+}
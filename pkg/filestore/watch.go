@@ -0,0 +1,68 @@
+package filestore
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often WatchFile polls for changes.
+var watchInterval = 500 * time.Millisecond
+
+// WatchFile calls fn whenever the file at p changes (mtime, size or existence).
+// Changes are detected by polling. The returned stop func is idempotent and
+// waits for the watcher goroutine to exit; it must not be called from fn.
+func (s Store) WatchFile(p string, fn func()) (stop func(), err error) {
+	abs, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	last, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(watchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			cur, err := os.Stat(abs)
+			if err != nil {
+				cur = nil
+			}
+			if !fileChanged(last, cur) {
+				continue
+			}
+			last = cur
+			select {
+			case <-done:
+				return
+			default:
+				fn()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}, nil
+}
+
+func fileChanged(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return (a == nil) != (b == nil)
+	}
+	return !a.ModTime().Equal(b.ModTime()) || a.Size() != b.Size() || a.Mode() != b.Mode()
+}
@@ -0,0 +1,120 @@
+package filestore
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/SamuelDBines/go-helpers/pkg/zipkit"
+)
+
+// Zip archives every regular file under srcDir into zipPath, with entry
+// names relative to srcDir.
+func (s Store) Zip(srcDir, zipPath string) error {
+	root, err := s.path(srcDir)
+	if err != nil {
+		return err
+	}
+	dst, err := s.path(zipPath)
+	if err != nil {
+		return err
+	}
+
+	var files []zipkit.FileToZip
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || p == dst {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, zipkit.FileToZip{Name: rel, Path: p})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), s.dirPerm()); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := zipkit.WriteZip(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Unzip extracts zipPath into dstDir. Entries that would land outside dstDir
+// (zip-slip) are rejected with ErrOutsideRoot before anything is written.
+func (s Store) Unzip(zipPath, dstDir string) error {
+	src, err := s.path(zipPath)
+	if err != nil {
+		return err
+	}
+	dst, err := s.path(dstDir)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return fmt.Errorf("%w: zip entry %s", ErrOutsideRoot, f.Name)
+		}
+	}
+	for _, f := range zr.File {
+		if err := s.extract(f, filepath.Join(dst, filepath.FromSlash(f.Name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Store) extract(f *zip.File, target string) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return os.MkdirAll(target, s.dirPerm())
+	}
+	if !mode.IsRegular() {
+		return nil // symlinks and devices are skipped
+	}
+	if err := os.MkdirAll(filepath.Dir(target), s.dirPerm()); err != nil {
+		return err
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = s.filePerm(nil)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
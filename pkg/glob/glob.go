@@ -50,7 +50,19 @@ func matchDoubleStar(pattern, path string) bool {
 	}
 
 	return strings.HasSuffix(path, suffix) || path == suffix ||
-		strings.HasSuffix(path, "/"+suffix)
+		strings.HasSuffix(path, "/"+suffix) || matchTail(suffix, path)
+}
+
+// matchTail reports whether suffix, which may hold * ? [...] wildcards,
+// matches the trailing segments of path (e.g. "*.tmp" against "a/b.tmp").
+func matchTail(suffix, path string) bool {
+	n := strings.Count(suffix, "/") + 1
+	segs := strings.Split(path, "/")
+	if len(segs) < n {
+		return false
+	}
+	ok, err := filepath.Match(suffix, strings.Join(segs[len(segs)-n:], "/"))
+	return err == nil && ok
 }
 
 func normalizeGlobRoot(p string) string {
@@ -0,0 +1,45 @@
+package glob
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		// no "**": plain filepath.Match, one segment per *
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/?.go", "cmd/ab.go", false},
+
+		// "**" prefixes and literal suffixes
+		{"**", "a/b/c.txt", true},
+		{"src/**", "src/a/b.go", true},
+		{"src/**", "srcx/a.go", false},
+		{"src/**", "lib/a.go", false},
+		{"**/main.go", "cmd/app/main.go", true},
+		{"**/main.go", "main.go", true},
+		{"**/main.go", "cmd/app/other.go", false},
+		{"src/**/util/x.go", "src/a/b/util/x.go", true},
+		{"src/**/util/x.go", "lib/a/util/x.go", false},
+
+		// wildcards after "**" apply to the trailing segments
+		{"**/*.tmp", "a.tmp", true},
+		{"**/*.tmp", "cache/x/a.tmp", true},
+		{"**/*.tmp", "cache/a.tmp.bak", false},
+		{"cache/**/*.tmp", "cache/x/a.tmp", true},
+		{"cache/**/*.tmp", "other/x/a.tmp", false},
+		{"**/build/*.o", "pkg/build/a.o", true},
+		{"**/build/*.o", "pkg/out/a.o", false},
+		{"**/[ab].txt", "x/b.txt", true},
+		{"**/[ab].txt", "x/c.txt", false},
+		{"**/a/**/*.go", "x/a/y/z.go", true},
+		{"**/a/**/*.go", "x/b/y/z.go", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// DurationBucket maps d to a coarse latency bucket. Lower bounds are
+// inclusive: 10ms is "10-100ms" and 1s is ">1s".
+func DurationBucket(d time.Duration) string {
+	switch {
+	case d < 10*time.Millisecond:
+		return "<10ms"
+	case d < 100*time.Millisecond:
+		return "10-100ms"
+	case d < time.Second:
+		return "100ms-1s"
+	default:
+		return ">1s"
+	}
+}
+
+// DurationAttrs returns key=d plus key_bucket=DurationBucket(d), for use as
+// log args: log.Info("done", logger.DurationAttrs("took", d)...).
+func DurationAttrs(key string, d time.Duration) []any {
+	return []any{
+		slog.Duration(key, d),
+		slog.String(key+"_bucket", DurationBucket(d)),
+	}
+}
+
+// GroupMap builds a group attr from m with keys sorted for stable output.
+func GroupMap(name string, m map[string]any) slog.Attr {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, m[k]))
+	}
+	return slog.Group(name, attrs...)
+}
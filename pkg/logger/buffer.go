@@ -0,0 +1,38 @@
+package logger
+
+import "sync"
+
+// buffer is a pooled byte slice the writers format a record into, so each
+// record costs one Write with no final string copy.
+type buffer []byte
+
+var bufPool = sync.Pool{New: func() any { b := make(buffer, 0, 1024); return &b }}
+
+func newBuffer() *buffer { return bufPool.Get().(*buffer) }
+
+// free returns b to the pool; oversized buffers are dropped to keep the
+// pool small.
+func (b *buffer) free() {
+	if cap(*b) > 64<<10 {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+func (b *buffer) WriteString(s string) (int, error) {
+	*b = append(*b, s...)
+	return len(s), nil
+}
+
+func (b *buffer) WriteByte(c byte) error {
+	*b = append(*b, c)
+	return nil
+}
+
+func (b *buffer) Len() int { return len(*b) }
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ChannelOptions configures NewChannel.
+type ChannelOptions struct {
+	Level  slog.Leveler
+	TimeFn func() time.Time
+	// Block waits for room on a full channel instead of dropping the record.
+	Block bool
+}
+
+// NewChannel returns a Handler that formats each record as text (without the
+// trailing newline) and sends it on a channel buffered to buf, e.g. for
+// asserting log output in tests or tailing logs over a connection. Records
+// are dropped when the channel is full unless ChannelOptions.Block is set.
+func NewChannel(buf int, opts ...ChannelOptions) (slog.Handler, <-chan string) {
+	var o ChannelOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	ch := make(chan string, buf)
+	h := &Handler{
+		out:    &chanWriter{ch: ch, block: o.Block},
+		level:  o.Level,
+		timeFn: o.TimeFn,
+	}
+	return h, ch
+}
+
+// chanWriter sends every Write as one message; writeText issues a single
+// write per record.
+type chanWriter struct {
+	ch    chan string
+	block bool
+}
+
+func (w *chanWriter) Write(p []byte) (int, error) {
+	s := strings.TrimSuffix(string(p), "\n")
+	if w.block {
+		w.ch <- s
+		return len(p), nil
+	}
+	select {
+	case w.ch <- s:
+	default: // full: drop
+	}
+	return len(p), nil
+}
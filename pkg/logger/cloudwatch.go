@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a Lambda request id, which the
+// CloudWatch format includes as "requestId".
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// NewLambda returns a logger using FormatCloudWatch. The logger name
+// defaults to AWS_LAMBDA_FUNCTION_NAME when set.
+func NewLambda(opts Options) *slog.Logger {
+	opts.Format = FormatCloudWatch
+	if opts.Name == "" {
+		opts.Name = os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	}
+	return New(opts)
+}
+
+// cloudWatchFields are the top-level keys writeCloudWatch sets itself.
+var cloudWatchFields = map[string]bool{
+	"@timestamp": true, "level": true, "logger": true,
+	"requestId": true, "source": true, "message": true,
+}
+
+func (h *Handler) writeCloudWatch(ctx context.Context, r slog.Record, src string) error {
+	b := newBuffer()
+	defer b.free()
+
+	b.WriteString(`{"@timestamp":`)
+	b.WriteString(jsonString(h.now().UTC().Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":`)
+	b.WriteString(jsonString(h.levelCase.apply(r.Level.String())))
+	if h.name != "" {
+		b.WriteString(`,"logger":`)
+		b.WriteString(jsonString(h.name))
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		b.WriteString(`,"requestId":`)
+		b.WriteString(jsonString(id))
+	}
+	if src != "" {
+		b.WriteString(`,"source":`)
+		b.WriteString(jsonString(src))
+	}
+	b.WriteString(`,"message":`)
+	b.WriteString(jsonString(r.Message))
+
+	// attrs are flattened to the top level so Insights can query them directly
+	attrs := flatten(h.collect(r))
+	if h.stable {
+		attrs = sortAttrs(attrs)
+	}
+	for _, a := range attrs {
+		key := a.Key
+		if cloudWatchFields[key] {
+			// keep the fixed fields unique; the JSON format nests attrs
+			// under "attrs", so use that as the prefix
+			key = "attrs." + key
+		}
+		b.WriteByte(',')
+		b.WriteString(jsonString(key))
+		b.WriteByte(':')
+		b.WriteString(jsonValue(a.Value))
+	}
+
+	b.WriteString("}\n")
+	_, err := h.writer().Write(*b)
+	return err
+}
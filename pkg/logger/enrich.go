@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewErrorEnricher wraps next so that records at slog.LevelError or above get
+// the attrs returned by fn. fn is only called for those records.
+func NewErrorEnricher(next slog.Handler, fn func() []slog.Attr) slog.Handler {
+	return &errorEnricher{next: next, fn: fn}
+}
+
+type errorEnricher struct {
+	next slog.Handler
+	fn   func() []slog.Attr
+}
+
+func (e *errorEnricher) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return e.next.Enabled(ctx, lvl)
+}
+
+func (e *errorEnricher) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		r = r.Clone()
+		r.AddAttrs(e.fn()...)
+	}
+	return e.next.Handle(ctx, r)
+}
+
+func (e *errorEnricher) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorEnricher{next: e.next.WithAttrs(attrs), fn: e.fn}
+}
+
+func (e *errorEnricher) WithGroup(name string) slog.Handler {
+	return &errorEnricher{next: e.next.WithGroup(name), fn: e.fn}
+}
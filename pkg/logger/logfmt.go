@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+func (h *Handler) writeLogfmt(r slog.Record, src string) error {
+	b := newBuffer()
+	defer b.free()
+
+	writeLogfmtPair(b, "time", h.now().Format(time.RFC3339Nano))
+	lc := h.levelCase
+	if lc == LevelCaseDefault {
+		lc = LevelCaseLower
+	}
+	writeLogfmtPair(b, "level", lc.apply(r.Level.String()))
+	if h.name != "" {
+		writeLogfmtPair(b, "logger", h.name)
+	}
+	if src != "" {
+		writeLogfmtPair(b, "source", src)
+	}
+	writeLogfmtPair(b, "msg", r.Message)
+	for _, a := range flatten(h.collect(r)) {
+		writeLogfmtPair(b, a.Key, logfmtValue(a.Value))
+	}
+
+	b.WriteByte('\n')
+	_, err := h.writer().Write(*b)
+	return err
+}
+
+func writeLogfmtPair(b *buffer, k, v string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtQuote(k))
+	b.WriteByte('=')
+	b.WriteString(logfmtQuote(v))
+}
+
+func logfmtValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	default:
+		return v.String()
+	}
+}
+
+// logfmtQuote quotes s if it is empty or contains spaces, quotes, '=' or
+// control characters.
+func logfmtQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == '\\' || r == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -8,17 +8,88 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 )
 
+// Format selects the output encoding of a Handler.
+type Format int
+
+const (
+	FormatText       Format = iota // human readable, optionally colored
+	FormatJSON                     // one JSON object per line, attrs nested under "attrs"
+	FormatCloudWatch               // flat JSON for CloudWatch Logs Insights
+	FormatLogfmt                   // key=value pairs (Loki, Heroku)
+)
+
+// LevelCase selects the casing of level names in output.
+type LevelCase int
+
+const (
+	LevelCaseDefault LevelCase = iota // upper, or lower for logfmt
+	LevelCaseUpper                    // INFO
+	LevelCaseLower                    // info
+	LevelCaseTitle                    // Info
+)
+
+func (c LevelCase) apply(s string) string {
+	switch c {
+	case LevelCaseUpper:
+		return strings.ToUpper(s)
+	case LevelCaseLower:
+		return strings.ToLower(s)
+	case LevelCaseTitle:
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+	default:
+		return s
+	}
+}
+
 type Options struct {
 	Name     string
 	Level    slog.Leveler
 	UseColor bool
-	JSON     bool
+	JSON     bool // shorthand for Format: FormatJSON
+	Format   Format
 	Out      io.Writer
 	TimeFn   func() time.Time
+
+	// StrictAttrs flags malformed key/value arguments (which slog records
+	// under "!BADKEY") by adding an attr_error attr to the record.
+	StrictAttrs bool
+
+	// SourceObject emits the JSON "source" as {"function","file","line"}
+	// like slog.Source instead of a compact string. Text output is unchanged.
+	SourceObject bool
+
+	// AttrsBeforeMsg puts text attrs in a [k=v ...] block before the
+	// message instead of after it. JSON output is unaffected.
+	AttrsBeforeMsg bool
+
+	// BaseAttrs are attached to every record, ahead of any With attrs,
+	// e.g. version and commit captured at startup.
+	BaseAttrs []slog.Attr
+
+	// PlainValues turns off per-type coloring of attr values in text output
+	// when UseColor is set.
+	PlainValues bool
+
+	// CallerSkip moves the reported source up that many frames past the
+	// logging call, so records logged through helper wrappers point at the
+	// wrapper's caller (1 for a single wrapper).
+	CallerSkip int
+
+	// LevelCase sets the casing of the level name in every format.
+	LevelCase LevelCase
+
+	// StableOrder sorts JSON and CloudWatch attrs by key, nested groups
+	// included, so output is byte-stable for golden tests. JSON top-level
+	// fields are always written as time, level, logger, source, msg, attrs.
+	StableOrder bool
 }
 
 func New(opts Options) *slog.Logger {
@@ -31,14 +102,26 @@ func New(opts Options) *slog.Logger {
 	if opts.TimeFn == nil {
 		opts.TimeFn = time.Now
 	}
+	if opts.JSON && opts.Format == FormatText {
+		opts.Format = FormatJSON
+	}
 
 	h := &Handler{
 		out:      opts.Out,
 		level:    opts.Level,
 		name:     opts.Name,
 		useColor: opts.UseColor,
-		json:     opts.JSON,
+		format:   opts.Format,
 		timeFn:   opts.TimeFn,
+		strict:   opts.StrictAttrs,
+		srcObj:   opts.SourceObject,
+
+		attrsFirst: opts.AttrsBeforeMsg,
+		plainVals:  opts.PlainValues,
+		callerSkip: opts.CallerSkip,
+		levelCase:  opts.LevelCase,
+		stable:     opts.StableOrder,
+		attrs:      append([]slog.Attr{}, opts.BaseAttrs...),
 	}
 	return slog.New(h)
 }
@@ -48,27 +131,65 @@ type Handler struct {
 	level    slog.Leveler
 	name     string
 	useColor bool
-	json     bool
+	format   Format
 	timeFn   func() time.Time
+	strict   bool
+	srcObj   bool
+
+	attrsFirst bool
+	plainVals  bool
+	callerSkip int
+	levelCase  LevelCase
+	stable     bool
 
 	attrs  []slog.Attr
 	groups []string
 }
 
 func (h *Handler) Enabled(_ context.Context, lvl slog.Level) bool {
+	if h.level == nil {
+		return lvl >= slog.LevelInfo
+	}
 	return lvl >= h.level.Level()
 }
 
-func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+// now and writer let a zero-value Handler log with New's defaults.
+func (h *Handler) now() time.Time {
+	if h.timeFn == nil {
+		return time.Now()
+	}
+	return h.timeFn()
+}
+
+func (h *Handler) writer() io.Writer {
+	if h.out == nil {
+		return os.Stdout
+	}
+	return h.out
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.callerSkip > 0 {
+		r.PC = skipCallers(r.PC, h.callerSkip)
+	}
 	src := ""
 	if r.Level <= slog.LevelDebug || r.Level >= slog.LevelWarn {
 		src = formatSource(r.PC)
 	}
+	if h.strict {
+		r = h.checkBadKeys(r)
+	}
 
-	if h.json {
+	switch h.format {
+	case FormatJSON:
 		return h.writeJSON(r, src)
+	case FormatCloudWatch:
+		return h.writeCloudWatch(ctx, r, src)
+	case FormatLogfmt:
+		return h.writeLogfmt(r, src)
+	default:
+		return h.writeText(r, src)
 	}
-	return h.writeText(r, src)
 }
 
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
@@ -83,10 +204,65 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	return &n
 }
 
+// collect returns the handler and record attrs in order, with keys
+// prefixed by the open groups (group1.group2.key).
+func (h *Handler) collect(r slog.Record) []slog.Attr {
+	all := make([]slog.Attr, 0, len(h.attrs)+r.NumAttrs())
+	all = append(all, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		if !a.Equal(slog.Attr{}) {
+			all = append(all, a)
+		}
+		return true
+	})
+
+	if prefix := strings.Join(h.groups, "."); prefix != "" {
+		for i := range all {
+			if all[i].Key == "" {
+				all[i].Key = prefix
+			} else {
+				all[i].Key = prefix + "." + all[i].Key
+			}
+		}
+	}
+	return all
+}
+
+// flatten expands group-valued attrs into dotted keys for the flat formats.
+func flatten(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		out = appendFlat(out, "", a)
+	}
+	return out
+}
+
+func appendFlat(dst []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) { // slog convention: zero attrs are ignored
+		return dst
+	}
+	key := a.Key
+	switch {
+	case prefix == "":
+	case key == "": // inline group
+		key = prefix
+	default:
+		key = prefix + "." + key
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(dst, slog.Attr{Key: key, Value: a.Value})
+	}
+	for _, ga := range a.Value.Group() {
+		dst = appendFlat(dst, key, ga)
+	}
+	return dst
+}
+
 func (h *Handler) writeText(r slog.Record, src string) error {
-	ts := h.timeFn().Format(time.StampMilli)
+	ts := h.now().Format(time.StampMilli)
 
-	level := levelLabel(r.Level, h.useColor)
+	level := levelLabel(r.Level, h.useColor, h.levelCase)
 	name := ""
 	if h.name != "" {
 		name = faint(h.useColor, "["+h.name+"] ")
@@ -94,55 +270,59 @@ func (h *Handler) writeText(r slog.Record, src string) error {
 
 	msg := r.Message
 
-	var b strings.Builder
+	b := newBuffer()
+	defer b.free()
 
-	fmt.Fprintf(&b, "%s %s %s", faint(h.useColor, ts), level, name)
+	fmt.Fprintf(b, "%s %s %s", faint(h.useColor, ts), level, name)
 	if src != "" {
-		fmt.Fprintf(&b, "%s ", faint(h.useColor, src))
+		fmt.Fprintf(b, "%s ", faint(h.useColor, src))
 	}
-	b.WriteString(msg)
 
-	all := make([]slog.Attr, 0, len(h.attrs)+8)
-	all = append(all, h.attrs...)
-
-	r.Attrs(func(a slog.Attr) bool {
-		all = append(all, a)
-		return true
-	})
+	pairs := make([]string, 0, len(h.attrs)+r.NumAttrs())
+	for _, a := range flatten(h.collect(r)) {
+		pairs = append(pairs, faint(h.useColor, a.Key)+"="+valueColor(h.useColor && !h.plainVals, a.Value))
+	}
 
-	// group prefix e.g. group1.group2.key
-	prefix := strings.Join(h.groups, ".")
-	for _, a := range all {
-		// a = a.Resolve()
-		k := a.Key
-		if prefix != "" {
-			k = prefix + "." + k
+	if h.attrsFirst {
+		// INFO [k=v k2=v2] message
+		if len(pairs) > 0 {
+			fmt.Fprintf(b, "[%s] ", strings.Join(pairs, " "))
+		}
+		b.WriteString(msg)
+	} else {
+		b.WriteString(msg)
+		for _, p := range pairs {
+			b.WriteByte(' ')
+			b.WriteString(p)
 		}
-		// spacing and formatting
-		fmt.Fprintf(&b, " %s=%s", faint(h.useColor, k), formatValue(a.Value))
 	}
 
 	b.WriteByte('\n')
 
-	_, err := io.WriteString(h.out, b.String())
+	_, err := h.writer().Write(*b)
 	return err
 }
 
 func (h *Handler) writeJSON(r slog.Record, src string) error {
-	var b strings.Builder
-	ts := h.timeFn().Format(time.RFC3339Nano)
+	b := newBuffer()
+	defer b.free()
+	ts := h.now().Format(time.RFC3339Nano)
 
 	b.WriteString(`{"time":`)
 	b.WriteString(jsonString(ts))
 	b.WriteString(`,"level":`)
-	b.WriteString(jsonString(r.Level.String()))
+	b.WriteString(jsonString(h.levelCase.apply(r.Level.String())))
 	if h.name != "" {
 		b.WriteString(`,"logger":`)
 		b.WriteString(jsonString(h.name))
 	}
 	if src != "" {
 		b.WriteString(`,"source":`)
-		b.WriteString(jsonString(src))
+		if h.srcObj {
+			b.WriteString(jsonSource(r.PC))
+		} else {
+			b.WriteString(jsonString(src))
+		}
 	}
 	b.WriteString(`,"msg":`)
 	b.WriteString(jsonString(r.Message))
@@ -150,37 +330,49 @@ func (h *Handler) writeJSON(r slog.Record, src string) error {
 	// attrs
 	b.WriteString(`,"attrs":{`)
 
-	first := true
-	prefix := strings.Join(h.groups, ".")
-
-	emit := func(a slog.Attr) {
-		// a = a.Resolve()
-		k := a.Key
-		if prefix != "" {
-			k = prefix + "." + k
-		}
-		if !first {
+	attrs := h.collect(r)
+	if h.stable {
+		attrs = sortAttrs(attrs)
+	}
+	for i, a := range attrs {
+		if i > 0 {
 			b.WriteByte(',')
 		}
-		first = false
-		b.WriteString(jsonString(k))
+		b.WriteString(jsonString(a.Key))
 		b.WriteByte(':')
 		b.WriteString(jsonValue(a.Value))
 	}
 
-	for _, a := range h.attrs {
-		emit(a)
-	}
-	r.Attrs(func(a slog.Attr) bool {
-		emit(a)
-		return true
-	})
-
 	b.WriteString("}}\n")
-	_, err := io.WriteString(h.out, b.String())
+	_, err := h.writer().Write(*b)
 	return err
 }
 
+// badKey is the key slog uses for a value passed without a key.
+const badKey = "!BADKEY"
+
+// checkBadKeys flags values without a key in the handler's WithAttrs
+// attrs as well as the record's own.
+func (h *Handler) checkBadKeys(r slog.Record) slog.Record {
+	var bad []string
+	check := func(a slog.Attr) bool {
+		if a.Key == badKey {
+			bad = append(bad, a.Value.String())
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		check(a)
+	}
+	r.Attrs(check)
+	if len(bad) == 0 {
+		return r
+	}
+	r = r.Clone()
+	r.AddAttrs(slog.String("attr_error", "value without key: "+strings.Join(bad, ", ")))
+	return r
+}
+
 // ---------- formatting helpers ----------
 
 const (
@@ -190,6 +382,11 @@ const (
 	ansiBrightGreen  = "\x1b[92m"
 	ansiBrightYellow = "\x1b[93m"
 	ansiBrightBlue   = "\x1b[94m"
+
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
 )
 
 func faint(ok bool, s string) string {
@@ -206,16 +403,33 @@ func color(ok bool, c, s string) string {
 	return c + s + ansiReset
 }
 
-func levelLabel(lvl slog.Level, useColor bool) string {
+// valueColor formats v, colored by kind when ok.
+func valueColor(ok bool, v slog.Value) string {
+	s := formatValue(v)
+	switch v.Kind() {
+	case slog.KindString:
+		return color(ok, ansiCyan, s)
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		return color(ok, ansiMagenta, s)
+	case slog.KindBool:
+		return color(ok, ansiYellow, s)
+	case slog.KindDuration:
+		return color(ok, ansiBlue, s)
+	default:
+		return s
+	}
+}
+
+func levelLabel(lvl slog.Level, useColor bool, lc LevelCase) string {
 	switch {
 	case lvl <= slog.LevelDebug:
-		return color(useColor, ansiBrightBlue, "DEBUG")
+		return color(useColor, ansiBrightBlue, lc.apply("DEBUG"))
 	case lvl < slog.LevelWarn:
-		return color(useColor, ansiBrightGreen, "INFO ")
+		return color(useColor, ansiBrightGreen, lc.apply("INFO "))
 	case lvl < slog.LevelError:
-		return color(useColor, ansiBrightYellow, "WARN ")
+		return color(useColor, ansiBrightYellow, lc.apply("WARN "))
 	default:
-		return color(useColor, ansiBrightRed, "ERROR")
+		return color(useColor, ansiBrightRed, lc.apply("ERROR"))
 	}
 }
 
@@ -225,15 +439,16 @@ func formatSource(pc uintptr) string {
 		// but that adds overhead. Keep it simple: empty if not provided.
 		return ""
 	}
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
+	// CallersFrames (unlike FuncForPC) resolves inlined calls, so a small
+	// wrapper inlined into its caller still reports its own frame.
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if f.Function == "" {
 		return ""
 	}
 
-	file, line := fn.FileLine(pc)
-	file = filepath.Base(file)
+	file, line := filepath.Base(f.File), f.Line
 
-	funcName := fn.Name()
+	funcName := f.Function
 	if i := strings.LastIndex(funcName, "/"); i >= 0 {
 		funcName = funcName[i+1:]
 	}
@@ -243,6 +458,34 @@ func formatSource(pc uintptr) string {
 	return fmt.Sprintf("%s:%d %s()", file, line, funcName)
 }
 
+// skipCallers returns the pc skip frames above pc on the current stack.
+// Handle runs synchronously in the logging goroutine, so the frame slog
+// recorded is still live; pc is returned unchanged if it can't be found.
+func skipCallers(pc uintptr, skip int) uintptr {
+	if pc == 0 {
+		return 0
+	}
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i, p := range pcs[:n] {
+		if p == pc {
+			if i+skip < n {
+				return pcs[i+skip]
+			}
+			break
+		}
+	}
+	return pc
+}
+
+// jsonSource renders pc as an object matching slog.Source.
+func jsonSource(pc uintptr) string {
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	return fmt.Sprintf(`{"function":%s,"file":%s,"line":%d}`,
+		jsonString(f.Function), jsonString(f.File), f.Line)
+}
+
 func formatValue(v slog.Value) string {
 	switch v.Kind() {
 	case slog.KindString:
@@ -291,8 +534,37 @@ func jsonString(s string) string {
 	return b.String()
 }
 
+// sortAttrs returns a copy of attrs sorted by key, recursing into groups.
+// Equal keys keep their order.
+func sortAttrs(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(sortAttrs(a.Value.Group())...)
+		}
+		out[i] = a
+	}
+	slices.SortStableFunc(out, func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) })
+	return out
+}
+
 func jsonValue(v slog.Value) string {
+	v = v.Resolve()
 	switch v.Kind() {
+	case slog.KindGroup:
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, a := range v.Group() {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(jsonString(a.Key))
+			b.WriteByte(':')
+			b.WriteString(jsonValue(a.Value))
+		}
+		b.WriteByte('}')
+		return b.String()
 	case slog.KindString:
 		return jsonString(v.String())
 	case slog.KindInt64:
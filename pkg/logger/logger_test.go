@@ -0,0 +1,388 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixedTime() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+func TestStrictAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Options{Out: &buf, TimeFn: fixedTime, StrictAttrs: true})
+	// built as a slice so vet doesn't reject the deliberate odd arg count
+	args := []any{"user", "sam", "orphan"}
+	log.Info("odd", args...)
+
+	out := buf.String()
+	if !strings.Contains(out, `attr_error="value without key: orphan"`) {
+		t.Fatalf("expected attr_error, got %q", out)
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime}).Info("odd", args...)
+	if strings.Contains(buf.String(), "attr_error") {
+		t.Fatalf("unexpected attr_error without StrictAttrs: %q", buf.String())
+	}
+
+	buf.Reset()
+	log.With(args[2:]...).Info("with")
+	if !strings.Contains(buf.String(), `attr_error="value without key: orphan"`) {
+		t.Fatalf("expected attr_error for With attrs, got %q", buf.String())
+	}
+}
+
+func TestSourceObject(t *testing.T) {
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime, JSON: true, SourceObject: true}).Warn("careful")
+
+	var rec struct {
+		Source struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if !strings.HasSuffix(rec.Source.Function, "TestSourceObject") ||
+		!strings.HasSuffix(rec.Source.File, "logger_test.go") || rec.Source.Line == 0 {
+		t.Fatalf("unexpected source: %+v", rec.Source)
+	}
+}
+
+func TestCloudWatch(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLambda(Options{Out: &buf, TimeFn: fixedTime})
+	ctx := WithRequestID(context.Background(), "req-123")
+	log.InfoContext(ctx, "handled", "status", 200)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"@timestamp": "2024-01-02T03:04:05Z",
+		"level":      "INFO",
+		"message":    "handled",
+		"requestId":  "req-123",
+		"status":     float64(200),
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Fatalf("%s = %v, want %v (line %q)", k, rec[k], v, buf.String())
+		}
+	}
+
+	// attrs named like the fixed fields must not produce duplicate keys
+	buf.Reset()
+	log.Warn("real", "message", "attr", "level", 3)
+	rec = nil
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	want = map[string]any{
+		"message":       "real",
+		"level":         "WARN",
+		"attrs.message": "attr",
+		"attrs.level":   float64(3),
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Fatalf("%s = %v, want %v (line %q)", k, rec[k], v, buf.String())
+		}
+	}
+}
+
+func TestZeroHandler(t *testing.T) {
+	h := &Handler{}
+	if h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("zero handler should default to info level")
+	}
+
+	var buf bytes.Buffer
+	h = &Handler{out: &buf}
+	slog.New(h).Info("hello", "k", 1)
+	if !strings.Contains(buf.String(), `hello k=1`) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestDurationBucket(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                      "<10ms",
+		9 * time.Millisecond:   "<10ms",
+		10 * time.Millisecond:  "10-100ms",
+		99 * time.Millisecond:  "10-100ms",
+		100 * time.Millisecond: "100ms-1s",
+		999 * time.Millisecond: "100ms-1s",
+		time.Second:            ">1s",
+		90 * time.Second:       ">1s",
+	}
+	for d, want := range cases {
+		if got := DurationBucket(d); got != want {
+			t.Fatalf("DurationBucket(%v) = %q, want %q", d, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime}).Info("req", DurationAttrs("took", 42*time.Millisecond)...)
+	if !strings.Contains(buf.String(), `took=42ms took_bucket="10-100ms"`) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime, Format: FormatLogfmt}).Info("user signed in", "user", "sam d", "n", 3, "ok", true)
+
+	want := `time=2024-01-02T03:04:05Z level=info msg="user signed in" user="sam d" n=3 ok=true` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestGroupMap(t *testing.T) {
+	ctx := map[string]any{"user": "sam", "id": 7, "admin": false}
+
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime}).Info("x", GroupMap("ctx", ctx))
+	if !strings.Contains(buf.String(), `x ctx.admin=false ctx.id=7 ctx.user="sam"`) {
+		t.Fatalf("text output %q", buf.String())
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, JSON: true}).Info("x", GroupMap("ctx", ctx))
+	if !strings.Contains(buf.String(), `"attrs":{"ctx":{"admin":false,"id":7,"user":"sam"}}`) {
+		t.Fatalf("json output %q", buf.String())
+	}
+}
+
+func TestAttrsBeforeMsg(t *testing.T) {
+	render := func(before bool) string {
+		var buf bytes.Buffer
+		New(Options{Out: &buf, TimeFn: fixedTime, Name: "api", AttrsBeforeMsg: before}).Info("started", "port", 8080, "env", "dev")
+		return buf.String()
+	}
+	ts := fixedTime().Format(time.StampMilli)
+	if got, want := render(false), ts+` INFO  [api] started port=8080 env="dev"`+"\n"; got != want {
+		t.Fatalf("default layout\ngot  %q\nwant %q", got, want)
+	}
+	if got, want := render(true), ts+` INFO  [api] [port=8080 env="dev"] started`+"\n"; got != want {
+		t.Fatalf("attrs-first layout\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestErrorEnricher(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	base := New(Options{Out: &buf, TimeFn: fixedTime}).Handler()
+	log := slog.New(NewErrorEnricher(base, func() []slog.Attr {
+		calls++
+		return []slog.Attr{slog.String("snapshot", "abc")}
+	}))
+
+	log.Info("fine")
+	if strings.Contains(buf.String(), "snapshot") || calls != 0 {
+		t.Fatalf("info record enriched: %q (calls=%d)", buf.String(), calls)
+	}
+	log.Error("boom")
+	if !strings.Contains(buf.String(), `boom snapshot="abc"`) || calls != 1 {
+		t.Fatalf("error record not enriched: %q (calls=%d)", buf.String(), calls)
+	}
+}
+
+func TestBaseAttrs(t *testing.T) {
+	base := []slog.Attr{slog.String("version", "1.2.3"), slog.String("commit", "abc123")}
+
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime, BaseAttrs: base}).With("req", 1).Info("hi", "k", "v")
+	if !strings.Contains(buf.String(), `hi version="1.2.3" commit="abc123" req=1 k="v"`) {
+		t.Fatalf("text output %q", buf.String())
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, JSON: true, BaseAttrs: base}).Info("hi", "k", "v")
+	if !strings.Contains(buf.String(), `"attrs":{"version":"1.2.3","commit":"abc123","k":"v"}`) {
+		t.Fatalf("json output %q", buf.String())
+	}
+}
+
+func TestValueColors(t *testing.T) {
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime, UseColor: true}).Info("x", "s", "str", "n", 42)
+	out := buf.String()
+	if !strings.Contains(out, ansiCyan+`"str"`+ansiReset) || !strings.Contains(out, ansiMagenta+"42"+ansiReset) {
+		t.Fatalf("values not colored by type: %q", out)
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, UseColor: true, PlainValues: true}).Info("x", "n", 42)
+	if strings.Contains(buf.String(), ansiMagenta) || !strings.Contains(buf.String(), "=42") {
+		t.Fatalf("PlainValues still colored: %q", buf.String())
+	}
+}
+
+func TestChannel(t *testing.T) {
+	h, ch := NewChannel(2, ChannelOptions{TimeFn: fixedTime})
+	log := slog.New(h)
+	log.Info("one", "n", 1)
+	log.Info("two")
+	log.Info("three") // channel full: dropped
+
+	want := []string{"one n=1", "two"}
+	for _, w := range want {
+		got := <-ch
+		if !strings.HasSuffix(got, w) {
+			t.Fatalf("got %q, want suffix %q", got, w)
+		}
+	}
+	select {
+	case got := <-ch:
+		t.Fatalf("expected drop when full, got %q", got)
+	default:
+	}
+
+	h, ch = NewChannel(0, ChannelOptions{TimeFn: fixedTime, Block: true})
+	go slog.New(h).Warn("blocked")
+	if got := <-ch; !strings.HasSuffix(got, "blocked") {
+		t.Fatalf("blocking send: got %q", got)
+	}
+}
+
+// warnVia is a one-level helper wrapper for TestCallerSkip.
+func warnVia(log *slog.Logger, msg string) { log.Warn(msg) }
+
+func TestCallerSkip(t *testing.T) {
+	var buf bytes.Buffer
+	warnVia(New(Options{Out: &buf, TimeFn: fixedTime, CallerSkip: 1}), "wrapped")
+	if out := buf.String(); !strings.Contains(out, "TestCallerSkip()") || strings.Contains(out, "warnVia") {
+		t.Fatalf("source should be the test, got %q", out)
+	}
+
+	buf.Reset()
+	warnVia(New(Options{Out: &buf, TimeFn: fixedTime}), "wrapped")
+	if out := buf.String(); !strings.Contains(out, "warnVia()") {
+		t.Fatalf("without CallerSkip source should be the wrapper, got %q", out)
+	}
+}
+
+func TestNestedGroupsText(t *testing.T) {
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime}).WithGroup("app").Info("req",
+		slog.Group("req", slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200))),
+		slog.Group("empty"), slog.Attr{})
+
+	out := strings.TrimSuffix(buf.String(), "\n")
+	if want := ` req app.req.http.method="GET" app.req.http.status=200`; !strings.HasSuffix(out, want) {
+		t.Fatalf("got %q, want suffix %q", out, want)
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, Format: FormatLogfmt}).Info("req",
+		slog.Group("req", slog.Group("http", slog.String("method", "GET"))))
+	if !strings.Contains(buf.String(), "req.http.method=GET") {
+		t.Fatalf("logfmt: %q", buf.String())
+	}
+}
+
+func TestLevelCase(t *testing.T) {
+	var buf bytes.Buffer
+	New(Options{Out: &buf, TimeFn: fixedTime, JSON: true, LevelCase: LevelCaseLower}).Warn("x")
+	if !strings.Contains(buf.String(), `"level":"warn"`) {
+		t.Fatalf("json: %q", buf.String())
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, LevelCase: LevelCaseTitle}).Info("x")
+	if !strings.Contains(buf.String(), " Info  x") {
+		t.Fatalf("text: %q", buf.String())
+	}
+
+	buf.Reset()
+	New(Options{Out: &buf, TimeFn: fixedTime, JSON: true}).Info("x")
+	if !strings.Contains(buf.String(), `"level":"INFO"`) {
+		t.Fatalf("default json: %q", buf.String())
+	}
+}
+
+func TestStableOrder(t *testing.T) {
+	const golden = `{"time":"2024-01-02T03:04:05Z","level":"INFO","logger":"api","msg":"done",` +
+		`"attrs":{"a":1,"m":{"x":true,"y":"2"},"z":"last"}}` + "\n"
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		log := New(Options{Out: &buf, TimeFn: fixedTime, JSON: true, Name: "api", StableOrder: true})
+		log.With("z", "last").Info("done", slog.Group("m", "y", "2", "x", true), "a", 1)
+		if got := buf.String(); got != golden {
+			t.Fatalf("run %d:\n got %s\nwant %s", i, got, golden)
+		}
+	}
+}
+
+type goldenCase struct {
+	name string
+	opts Options
+}
+
+func goldenCases() []goldenCase {
+	return []goldenCase{
+		{"text", Options{Name: "api"}},
+		{"text-color", Options{Name: "api", UseColor: true, AttrsBeforeMsg: true}},
+		{"json", Options{Name: "api", JSON: true}},
+		{"cloudwatch", Options{Name: "api", Format: FormatCloudWatch}},
+		{"logfmt", Options{Name: "api", Format: FormatLogfmt}},
+	}
+}
+
+func goldenLog(log *slog.Logger) {
+	log = log.With("svc", "billing").WithGroup("req")
+	log.Info("handled", "path", "/v1/pay", "status", 200, "ok", true, "dur", 1500*time.Millisecond,
+		slog.Group("user", "id", 7, "name", `Sam "S"`))
+	log.Error("failed", "err", "boom\nline2", "ratio", 0.25)
+}
+
+// goldenOutput was captured from the strings.Builder implementation; the
+// pooled-buffer writers must match it byte for byte.
+var goldenOutput = map[string]string{
+	"text":       "Jan  2 03:04:05.000 INFO  [api] handled req.svc=\"billing\" req.path=\"/v1/pay\" req.status=200 req.ok=true req.dur=1.5s req.user.id=7 req.user.name=\"Sam \\\"S\\\"\"\nJan  2 03:04:05.000 ERROR [api] logger_test.go:N logger.goldenLog() failed req.svc=\"billing\" req.err=\"boom\\nline2\" req.ratio=0.25\n",
+	"text-color": "\x1b[2mJan  2 03:04:05.000\x1b[0m \x1b[92mINFO \x1b[0m \x1b[2m[api] \x1b[0m[\x1b[2mreq.svc\x1b[0m=\x1b[36m\"billing\"\x1b[0m \x1b[2mreq.path\x1b[0m=\x1b[36m\"/v1/pay\"\x1b[0m \x1b[2mreq.status\x1b[0m=\x1b[35m200\x1b[0m \x1b[2mreq.ok\x1b[0m=\x1b[33mtrue\x1b[0m \x1b[2mreq.dur\x1b[0m=\x1b[34m1.5s\x1b[0m \x1b[2mreq.user.id\x1b[0m=\x1b[35m7\x1b[0m \x1b[2mreq.user.name\x1b[0m=\x1b[36m\"Sam \\\"S\\\"\"\x1b[0m] handled\n\x1b[2mJan  2 03:04:05.000\x1b[0m \x1b[91mERROR\x1b[0m \x1b[2m[api] \x1b[0m\x1b[2mlogger_test.go:N logger.goldenLog()\x1b[0m [\x1b[2mreq.svc\x1b[0m=\x1b[36m\"billing\"\x1b[0m \x1b[2mreq.err\x1b[0m=\x1b[36m\"boom\\nline2\"\x1b[0m \x1b[2mreq.ratio\x1b[0m=\x1b[35m0.25\x1b[0m] failed\n",
+	"json":       "{\"time\":\"2024-01-02T03:04:05Z\",\"level\":\"INFO\",\"logger\":\"api\",\"msg\":\"handled\",\"attrs\":{\"req.svc\":\"billing\",\"req.path\":\"/v1/pay\",\"req.status\":200,\"req.ok\":true,\"req.dur\":\"1.5s\",\"req.user\":{\"id\":7,\"name\":\"Sam \\\"S\\\"\"}}}\n{\"time\":\"2024-01-02T03:04:05Z\",\"level\":\"ERROR\",\"logger\":\"api\",\"source\":\"logger_test.go:N logger.goldenLog()\",\"msg\":\"failed\",\"attrs\":{\"req.svc\":\"billing\",\"req.err\":\"boom\\nline2\",\"req.ratio\":0.25}}\n",
+	"cloudwatch": "{\"@timestamp\":\"2024-01-02T03:04:05Z\",\"level\":\"INFO\",\"logger\":\"api\",\"message\":\"handled\",\"req.svc\":\"billing\",\"req.path\":\"/v1/pay\",\"req.status\":200,\"req.ok\":true,\"req.dur\":\"1.5s\",\"req.user.id\":7,\"req.user.name\":\"Sam \\\"S\\\"\"}\n{\"@timestamp\":\"2024-01-02T03:04:05Z\",\"level\":\"ERROR\",\"logger\":\"api\",\"source\":\"logger_test.go:N logger.goldenLog()\",\"message\":\"failed\",\"req.svc\":\"billing\",\"req.err\":\"boom\\nline2\",\"req.ratio\":0.25}\n",
+	"logfmt":     "time=2024-01-02T03:04:05Z level=info logger=api msg=handled req.svc=billing req.path=/v1/pay req.status=200 req.ok=true req.dur=1.5s req.user.id=7 req.user.name=\"Sam \\\"S\\\"\"\ntime=2024-01-02T03:04:05Z level=error logger=api source=\"logger_test.go:N logger.goldenLog()\" msg=failed req.svc=billing req.err=\"boom\\nline2\" req.ratio=0.25\n",
+}
+
+var srcLineRe = regexp.MustCompile(`logger_test\.go:\d+`)
+
+func TestGoldenOutput(t *testing.T) {
+	for _, c := range goldenCases() {
+		var buf bytes.Buffer
+		c.opts.Out = &buf
+		c.opts.TimeFn = fixedTime
+		goldenLog(New(c.opts))
+		if got := srcLineRe.ReplaceAllString(buf.String(), "logger_test.go:N"); got != goldenOutput[c.name] {
+			t.Errorf("%s:\n got %q\nwant %q", c.name, got, goldenOutput[c.name])
+		}
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	for _, c := range goldenCases() {
+		b.Run(c.name, func(b *testing.B) {
+			c.opts.Out = io.Discard
+			c.opts.TimeFn = fixedTime
+			log := New(c.opts).With("svc", "billing")
+			b.ReportAllocs()
+			for b.Loop() {
+				log.Info("handled", "path", "/v1/pay", "status", 200, "ok", true)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package yamlw
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type jsonFrame struct {
+	close byte
+	n     int // members written so far
+}
+
+// jsonBegin starts a member of the current container, opening the root
+// container on first use ({} for keyed members, [] otherwise), and writes
+// the comma and "key": as needed.
+func (y *Builder) jsonBegin(key string, hasKey bool) {
+	if len(y.stack) == 0 {
+		if hasKey {
+			y.jsonPush('{')
+		} else {
+			y.jsonPush('[')
+		}
+	}
+	top := &y.stack[len(y.stack)-1]
+	if top.n > 0 {
+		y.b.WriteByte(',')
+	}
+	top.n++
+	if hasKey {
+		y.b.WriteString(jsonScalar(key))
+		y.b.WriteByte(':')
+	}
+}
+
+func (y *Builder) jsonPush(open byte) {
+	y.b.WriteByte(open)
+	close := byte('}')
+	if open == '[' {
+		close = ']'
+	}
+	y.stack = append(y.stack, jsonFrame{close: close})
+}
+
+func (y *Builder) jsonPop() {
+	top := y.stack[len(y.stack)-1]
+	y.stack = y.stack[:len(y.stack)-1]
+	y.b.WriteByte(top.close)
+}
+
+// jsonAny writes v in value position.
+func (y *Builder) jsonAny(v any) {
+	switch v.(type) {
+	case nil:
+		y.b.WriteString("null")
+	case map[string]string, map[string]any, Marshaler:
+		y.jsonPush('{')
+		y.Any(v)
+		y.jsonPop()
+	case []string, []any:
+		y.jsonPush('[')
+		y.Any(v)
+		y.jsonPop()
+	default:
+		if !isScalar(v) {
+			v = fmt.Sprint(v)
+		}
+		y.b.WriteString(jsonScalar(v))
+	}
+}
+
+// jsonString closes any containers still open without changing the builder.
+func (y *Builder) jsonString() string {
+	if y.b.Len() == 0 {
+		return "{}"
+	}
+	var b strings.Builder
+	b.WriteString(y.b.String())
+	for i := len(y.stack) - 1; i >= 0; i-- {
+		b.WriteByte(y.stack[i].close)
+	}
+	return b.String()
+}
+
+func jsonScalar(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// NaN and ±Inf have no JSON form
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return string(b)
+}
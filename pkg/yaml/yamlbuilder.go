@@ -2,18 +2,81 @@ package yamlw
 
 import (
 	"fmt"
+	"math"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 type Builder struct {
+	// BoolStyle controls how booleans are rendered. Defaults to true/false.
+	BoolStyle BoolStyle
+	// Strict double-quotes every mapping key and string value and writes
+	// numbers and bools in canonical YAML 1.2 form (BoolStyle is ignored).
+	Strict bool
+	// JSONMode emits compact JSON instead of YAML from the same KV/Map/
+	// List/Item/Any calls. ComplexKey and Line are not supported.
+	JSONMode bool
+
 	b      strings.Builder
 	indent int
+	stack  []jsonFrame // open JSON containers
 }
 
+// BoolStyle selects the spelling used for boolean scalars.
+type BoolStyle int
+
+const (
+	BoolLower BoolStyle = iota // true / false
+	BoolTitle                  // True / False
+	BoolYesNo                  // yes / no
+	BoolOnOff                  // on / off
+)
+
 func New() *Builder { return &Builder{} }
 
-func (y *Builder) String() string { return y.b.String() }
+func (y *Builder) String() string {
+	if y.JSONMode {
+		return y.jsonString()
+	}
+	return y.b.String()
+}
+
+// Lines returns the output split into lines, without trailing newlines.
+// Handy for asserting indentation in tests.
+func (y *Builder) Lines() []string {
+	out := strings.TrimSuffix(y.String(), "\n")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// Reset clears the output and indentation so the builder can be reused.
+// Options such as BoolStyle are kept.
+func (y *Builder) Reset() {
+	y.b.Reset()
+	y.indent = 0
+	y.stack = y.stack[:0]
+}
+
+var pool = sync.Pool{New: func() any { return New() }}
+
+// Get returns an empty Builder with default options from a shared pool.
+// Return it with Put once the output has been consumed.
+func Get() *Builder {
+	y := pool.Get().(*Builder)
+	y.Reset()
+	y.BoolStyle = BoolLower
+	y.Strict = false
+	y.JSONMode = false
+	return y
+}
+
+// Put returns a Builder to the pool. It must not be used afterwards.
+func Put(y *Builder) { pool.Put(y) }
 
 func (y *Builder) Indent(fn func()) {
 	y.indent++
@@ -30,28 +93,114 @@ func (y *Builder) line(s string) {
 }
 
 func (y *Builder) KV(key string, val any) {
+	if y.JSONMode {
+		y.jsonBegin(key, true)
+		y.jsonAny(val)
+		return
+	}
 	// key: <scalar>
 	if isNil(val) {
-		y.line(fmt.Sprintf("%s: null", key))
+		y.line(fmt.Sprintf("%s: null", y.key(key)))
 		return
 	}
 	if isScalar(val) {
-		y.line(fmt.Sprintf("%s: %s", key, scalar(val)))
+		y.line(fmt.Sprintf("%s: %s", y.key(key), y.scalar(val)))
 		return
 	}
 
 	// key:
-	y.line(fmt.Sprintf("%s:", key))
+	y.line(y.key(key) + ":")
 	y.Indent(func() { y.Any(val) })
 }
 
+var quantityRe = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+|[numkMGTPE]|[KMGTPE]i)?$`)
+
+// Quantity writes a Kubernetes resource quantity (100m, 1Gi, 500Mi, ...)
+// unquoted, or quoted like any other string in Strict mode. Nothing is
+// written if val is not a valid quantity.
+func (y *Builder) Quantity(key, val string) error {
+	if !quantityRe.MatchString(val) {
+		return fmt.Errorf("yaml: invalid quantity %q for %s", val, key)
+	}
+	if y.JSONMode || y.Strict {
+		y.KV(key, val)
+		return nil
+	}
+	y.line(fmt.Sprintf("%s: %s", y.key(key), val))
+	return nil
+}
+
+// Wrapped writes text as a folded block scalar (">-") broken at spaces so
+// content lines stay within width columns where possible; a YAML parser
+// folds it back into the original single line. Text that folding cannot
+// represent exactly (newlines, leading/trailing or doubled spaces),
+// JSONMode and Strict mode fall back to KV.
+func (y *Builder) Wrapped(key, text string, width int) {
+	if y.JSONMode || y.Strict || text == "" || strings.ContainsAny(text, "\n\r\t") ||
+		strings.Contains(text, "  ") || strings.TrimSpace(text) != text {
+		y.KV(key, text)
+		return
+	}
+	y.line(y.key(key) + ": >-")
+	y.Indent(func() {
+		avail := width - 2*y.indent
+		var cur strings.Builder
+		for _, w := range strings.Split(text, " ") {
+			if cur.Len() > 0 && cur.Len()+1+len(w) > avail {
+				y.line(cur.String())
+				cur.Reset()
+			}
+			if cur.Len() > 0 {
+				cur.WriteByte(' ')
+			}
+			cur.WriteString(w)
+		}
+		y.line(cur.String())
+	})
+}
+
 func (y *Builder) Map(key string, fn func()) {
-	y.line(key + ":")
+	if y.JSONMode {
+		y.jsonBegin(key, true)
+		y.jsonPush('{')
+		fn()
+		y.jsonPop()
+		return
+	}
+	y.line(y.key(key) + ":")
 	y.Indent(fn)
 }
 
+// MergedMap writes base with overrides applied under key, keys sorted. The
+// merge is shallow: an override replaces the whole base value for its key,
+// nested maps included. Neither input is modified.
+func (y *Builder) MergedMap(key string, base, overrides map[string]any) {
+	m := make(map[string]any, len(base)+len(overrides))
+	for k, v := range base {
+		m[k] = v
+	}
+	for k, v := range overrides {
+		m[k] = v
+	}
+	y.Map(key, func() { writeAnyMap(y, m) })
+}
+
+// ComplexKey writes an explicit mapping entry ("? key" / ": value") so that
+// non-scalar nodes such as sequences can be used as keys.
+func (y *Builder) ComplexKey(keyFn func(), valFn func()) {
+	y.line("?")
+	y.Indent(keyFn)
+	y.line(":")
+	y.Indent(valFn)
+}
+
 func (y *Builder) List(key string, items []any) {
-	y.line(key + ":")
+	if y.JSONMode {
+		y.jsonBegin(key, true)
+		y.jsonAny(items)
+		return
+	}
+	y.line(y.key(key) + ":")
 	y.Indent(func() {
 		for _, it := range items {
 			y.Item(it)
@@ -62,12 +211,17 @@ func (y *Builder) List(key string, items []any) {
 func (y *Builder) Item(val any) {
 	// - <scalar> OR
 	// - <nested>
+	if y.JSONMode {
+		y.jsonBegin("", false)
+		y.jsonAny(val)
+		return
+	}
 	if isNil(val) {
 		y.line("- null")
 		return
 	}
 	if isScalar(val) {
-		y.line("- " + scalar(val))
+		y.line("- " + y.scalar(val))
 		return
 	}
 	y.line("-")
@@ -82,7 +236,11 @@ func (y *Builder) Any(v any) {
 		writeAnyMap(y, t)
 	case []string:
 		for _, s := range t {
-			y.line("- " + scalar(s))
+			if y.JSONMode {
+				y.Item(s)
+				continue
+			}
+			y.line("- " + y.scalar(s))
 		}
 	case []any:
 		for _, it := range t {
@@ -92,7 +250,7 @@ func (y *Builder) Any(v any) {
 		t.YAML(y)
 	default:
 		// fall back to scalar-ish fmt
-		y.line(scalar(fmt.Sprint(v)))
+		y.line(y.scalar(fmt.Sprint(v)))
 	}
 }
 
@@ -119,21 +277,85 @@ func isScalar(v any) bool {
 
 func (y *Builder) Line(s string) { y.line(s) }
 
-func scalar(v any) string {
+// key renders a mapping key, quoted in Strict mode.
+func (y *Builder) key(k string) string {
+	if y.Strict {
+		return strconv.Quote(k)
+	}
+	return k
+}
+
+func (y *Builder) scalar(v any) string {
+	if y.Strict {
+		return strictScalar(v)
+	}
 	switch t := v.(type) {
 	case string:
 		return quoteIfNeeded(t)
 	case bool:
-		if t {
-			return "true"
-		}
-		return "false"
+		return y.BoolStyle.format(t)
 	default:
 		// numbers, etc
 		return fmt.Sprint(v)
 	}
 }
 
+func strictScalar(v any) string {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case float32:
+		return canonicalFloat(float64(t), 32)
+	case float64:
+		return canonicalFloat(t, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func canonicalFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0" // keep it a float, not an int
+	}
+	return s
+}
+
+func (st BoolStyle) format(b bool) string {
+	switch st {
+	case BoolTitle:
+		if b {
+			return "True"
+		}
+		return "False"
+	case BoolYesNo:
+		if b {
+			return "yes"
+		}
+		return "no"
+	case BoolOnOff:
+		if b {
+			return "on"
+		}
+		return "off"
+	default:
+		if b {
+			return "true"
+		}
+		return "false"
+	}
+}
+
 func quoteIfNeeded(s string) string {
 	// Safe-ish YAML scalar quoting for k8s fields
 	if s == "" ||
@@ -0,0 +1,194 @@
+package yamlw
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBoolStyle(t *testing.T) {
+	cases := []struct {
+		style BoolStyle
+		want  string
+	}{
+		{BoolLower, "on: true\noff: false\n"},
+		{BoolTitle, "on: True\noff: False\n"},
+		{BoolYesNo, "on: yes\noff: no\n"},
+		{BoolOnOff, "on: on\noff: off\n"},
+	}
+	for _, c := range cases {
+		y := New()
+		y.BoolStyle = c.style
+		y.KV("on", true)
+		y.KV("off", false)
+		if got := y.String(); got != c.want {
+			t.Fatalf("style %d: got %q want %q", c.style, got, c.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	y := New()
+	y.Map("first", func() { y.KV("a", 1) })
+	y.Reset()
+	y.KV("b", 2)
+	if got := y.String(); got != "b: 2\n" {
+		t.Fatalf("residue after Reset: %q", got)
+	}
+
+	p := Get()
+	p.BoolStyle = BoolYesNo
+	p.KV("x", true)
+	Put(p)
+	p = Get()
+	p.KV("y", true)
+	if got := p.String(); got != "y: true\n" {
+		t.Fatalf("pooled builder not reset: %q", got)
+	}
+	Put(p)
+}
+
+func TestQuantity(t *testing.T) {
+	y := New()
+	for _, q := range []string{"100m", "1Gi", "500Mi"} {
+		if err := y.Quantity("q", q); err != nil {
+			t.Fatalf("Quantity(%q): %v", q, err)
+		}
+	}
+	if got, want := y.String(), "q: 100m\nq: 1Gi\nq: 500Mi\n"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if err := y.Quantity("q", "1GGi"); err == nil {
+		t.Fatal("expected error for 1GGi")
+	}
+	if got := y.String(); strings.Contains(got, "1GGi") {
+		t.Fatalf("invalid quantity was written: %q", got)
+	}
+}
+
+func TestComplexKey(t *testing.T) {
+	y := New()
+	y.ComplexKey(
+		func() { y.Any([]string{"a", "b"}) },
+		func() { y.KV("x", 1) },
+	)
+	want := "?\n  - a\n  - b\n:\n  x: 1\n"
+	if got := y.String(); got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	m := map[string]any{"name": "web", "on": "yes", "replicas": 3, "ratio": 2.0, "debug": true}
+
+	y := New()
+	y.BoolStyle = BoolYesNo
+	y.Any(m)
+	want := "debug: yes\nname: web\non: yes\nratio: 2\nreplicas: 3\n"
+	if got := y.String(); got != want {
+		t.Fatalf("default\ngot  %q\nwant %q", got, want)
+	}
+
+	y = New()
+	y.BoolStyle = BoolYesNo
+	y.Strict = true
+	y.Any(m)
+	want = "\"debug\": true\n\"name\": \"web\"\n\"on\": \"yes\"\n\"ratio\": 2.0\n\"replicas\": 3\n"
+	if got := y.String(); got != want {
+		t.Fatalf("strict\ngot  %q\nwant %q", got, want)
+	}
+
+	y = New()
+	y.Strict = true
+	if err := y.Quantity("cpu", "100m"); err != nil {
+		t.Fatal(err)
+	}
+	y.Wrapped("description", "a long line of text", 10)
+	want = "\"cpu\": \"100m\"\n\"description\": \"a long line of text\"\n"
+	if got := y.String(); got != want {
+		t.Fatalf("strict quantity/wrapped\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestJSONMode(t *testing.T) {
+	y := New()
+	y.JSONMode = true
+	y.KV("name", "web")
+	y.Map("spec", func() {
+		y.KV("replicas", 3)
+		y.List("ports", []any{80, map[string]any{"port": 443, "tls": true}})
+		y.KV("labels", map[string]string{"app": "web", "tier": "front"})
+	})
+	y.KV("notes", nil)
+
+	out := y.String()
+	if !json.Valid([]byte(out)) {
+		t.Fatalf("invalid JSON: %s", out)
+	}
+	want := `{"name":"web","spec":{"replicas":3,"ports":[80,{"port":443,"tls":true}],"labels":{"app":"web","tier":"front"}},"notes":null}`
+	if out != want {
+		t.Fatalf("got  %s\nwant %s", out, want)
+	}
+}
+
+func TestLines(t *testing.T) {
+	y := New()
+	y.Map("metadata", func() {
+		y.KV("name", "web")
+		y.KV("labels", map[string]string{"app": "web"})
+	})
+	want := []string{"metadata:", "  name: web", "  labels:", "    app: web"}
+	if got := y.Lines(); !slices.Equal(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if New().Lines() != nil {
+		t.Fatal("empty builder should have no lines")
+	}
+}
+
+func TestWrapped(t *testing.T) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 5) + "end"
+	y := New()
+	y.Map("spec", func() { y.Wrapped("description", text, 40) })
+
+	lines := y.Lines()
+	if lines[1] != "  description: >-" {
+		t.Fatalf("header = %q", lines[1])
+	}
+	// re-read: strip the block indentation and fold line breaks into spaces
+	var body []string
+	for _, l := range lines[2:] {
+		if len(l) > 40 {
+			t.Fatalf("line over width: %q", l)
+		}
+		if !strings.HasPrefix(l, "    ") || l[4] == ' ' {
+			t.Fatalf("bad indentation: %q", l)
+		}
+		body = append(body, l[4:])
+	}
+	if got := strings.Join(body, " "); got != text {
+		t.Fatalf("folded text = %q, want %q", got, text)
+	}
+
+	y.Reset()
+	y.Wrapped("k", " padded", 40)
+	if got := y.String(); got != "k: \" padded\"\n" {
+		t.Fatalf("fallback = %q", got)
+	}
+}
+
+func TestMergedMap(t *testing.T) {
+	base := map[string]any{"replicas": 1, "image": "app:1", "env": map[string]any{"A": "1"}}
+	over := map[string]any{"replicas": 3, "env": map[string]any{"B": "2"}, "port": 8080}
+	y := New()
+	y.MergedMap("spec", base, over)
+
+	want := []string{"spec:", "  env:", "    B: 2", `  image: "app:1"`, "  port: 8080", "  replicas: 3"}
+	if got := y.Lines(); !slices.Equal(got, want) {
+		t.Fatalf("got %q", got)
+	}
+	if base["replicas"] != 1 {
+		t.Fatal("base was modified")
+	}
+}